@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Startup configuration
+/*
+	Every setting can come from, in increasing order of precedence:
+
+	- its default
+	- a JSON config file given with -config or CONFIG_FILE, as an object
+	  keyed by the setting names below, e.g. {"DATABASE_BACKEND": "memory"}
+	- the environment variable of the same name
+	- the command line flag, lower case with dashes, e.g. -database-backend
+*/
+type Config struct {
+	Port string
+
+	DatabaseBackend string
+	DatabaseSource  string
+	DatabaseTimeout time.Duration
+
+	SinkCapacity          int
+	SinkIdleTimeout       time.Duration
+	SinkReconcileInterval time.Duration
+
+	ConfigCacheTTL         time.Duration
+	ConfigCacheNegativeTTL time.Duration
+	ConfigCacheMaxEntries  int
+
+	ShutdownTimeout time.Duration
+
+	SinkCheckToken string
+}
+
+type setting struct {
+	key      string
+	fallback string
+	usage    string
+}
+
+var settings = []setting{
+	{"PORT", "8080", "Port to listen on."},
+	{"DATABASE_BACKEND", "", "Database backend. Defaults to firestore when DATABASE_ID is set, memory otherwise."},
+	{"DATABASE_SOURCE", "", "Backend specific database location: a Firestore database id, a postgres:// URL or a bolt file path. Defaults to DATABASE_ID."},
+	{"DATABASE_ID", "", "Firestore database id, as projects/{project}/databases/{name}."},
+	{"DATABASE_TIMEOUT", "10s", "Deadline for the database calls of a request."},
+	{"SINK_CAPACITY", "100", "Maximum number of live sinks, 0 for no limit."},
+	{"SINK_IDLE_TIMEOUT", "15m", "Close sinks unused for this long, 0 to keep them."},
+	{"SINK_RECONCILE_INTERVAL", "1m", "How often sinks are checked against the stored configurations, 0 to disable."},
+	{"CONFIG_CACHE_TTL", "30s", "How long configurations are cached."},
+	{"CONFIG_CACHE_NEGATIVE_TTL", "10s", "How long unknown configuration ids are cached, 0 to disable."},
+	{"CONFIG_CACHE_MAX_ENTRIES", "10000", "Maximum number of cached configurations, 0 for no limit."},
+	{"SHUTDOWN_TIMEOUT", "9s", "Deadline for finishing requests and flushing sinks on shutdown."},
+	{"SINK_CHECK_TOKEN", "", "Bearer token required by the sink check endpoints, which are disabled when unset."},
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// LoadConfig reads the configuration from the command line arguments (without
+// the program name), the environment and the config file
+func LoadConfig(args []string) (Config, error) {
+	var config Config
+
+	flags := flag.NewFlagSet("webhook-connector", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "JSON config file.")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.key] = flags.String(flagName(s.key), "", s.usage)
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return config, err
+	}
+
+	// Defaults, then file, environment and flags
+	values := map[string]string{}
+	for _, s := range settings {
+		values[s.key] = s.fallback
+	}
+	if *configFile != "" {
+		fileValues, err := readConfigFile(*configFile)
+		if err != nil {
+			return config, err
+		}
+		for key, value := range fileValues {
+			values[key] = value
+		}
+	}
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.key); ok && value != "" {
+			values[s.key] = value
+		}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		for key, value := range flagValues {
+			if flagName(key) == f.Name {
+				values[key] = *value
+			}
+		}
+	})
+
+	return parseConfig(values)
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config file: %v", err)
+	}
+	var raw map[string]interface{}
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse config file: %v", err)
+	}
+
+	known := map[string]bool{}
+	for _, s := range settings {
+		known[s.key] = true
+	}
+	values := map[string]string{}
+	for key, value := range raw {
+		if !known[key] {
+			return nil, fmt.Errorf("Unknown setting %s in config file", key)
+		}
+		values[key] = fmt.Sprint(value)
+	}
+	return values, nil
+}
+
+func parseConfig(values map[string]string) (Config, error) {
+	config := Config{
+		Port:            values["PORT"],
+		DatabaseBackend: values["DATABASE_BACKEND"],
+		DatabaseSource:  values["DATABASE_SOURCE"],
+		SinkCheckToken:  values["SINK_CHECK_TOKEN"],
+	}
+
+	// DATABASE_ID predates backend selection and still selects Firestore
+	if config.DatabaseSource == "" {
+		config.DatabaseSource = values["DATABASE_ID"]
+	}
+	if config.DatabaseBackend == "" {
+		config.DatabaseBackend = "memory"
+		if values["DATABASE_ID"] != "" {
+			config.DatabaseBackend = "firestore"
+		}
+	}
+
+	var err error
+	ints := map[string]*int{
+		"SINK_CAPACITY":            &config.SinkCapacity,
+		"CONFIG_CACHE_MAX_ENTRIES": &config.ConfigCacheMaxEntries,
+	}
+	for key, target := range ints {
+		*target, err = strconv.Atoi(values[key])
+		if err != nil {
+			return config, fmt.Errorf("Invalid %s: %v", key, err)
+		}
+	}
+	durations := map[string]*time.Duration{
+		"DATABASE_TIMEOUT":          &config.DatabaseTimeout,
+		"SINK_IDLE_TIMEOUT":         &config.SinkIdleTimeout,
+		"SINK_RECONCILE_INTERVAL":   &config.SinkReconcileInterval,
+		"CONFIG_CACHE_TTL":          &config.ConfigCacheTTL,
+		"CONFIG_CACHE_NEGATIVE_TTL": &config.ConfigCacheNegativeTTL,
+		"SHUTDOWN_TIMEOUT":          &config.ShutdownTimeout,
+	}
+	for key, target := range durations {
+		*target, err = time.ParseDuration(values[key])
+		if err != nil {
+			return config, fmt.Errorf("Invalid %s: %v", key, err)
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"DATABASE_BACKEND": "firestore", "SINK_CAPACITY": 5, "PORT": "9000"}`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DATABASE_ID", "")
+	t.Setenv("SINK_CAPACITY", "7")
+	t.Setenv("PORT", "9001")
+
+	config, err := LoadConfig([]string{"-port", "9002", "-sink-idle-timeout", "1m"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.DatabaseBackend != "firestore" {
+		t.Fatalf("Expected the backend from the file, got %s", config.DatabaseBackend)
+	}
+	if config.SinkCapacity != 7 {
+		t.Fatalf("Expected the capacity from the environment, got %d", config.SinkCapacity)
+	}
+	if config.Port != "9002" || config.SinkIdleTimeout != time.Minute {
+		t.Fatalf("Expected the flags to win, got port %s and idle timeout %s", config.Port, config.SinkIdleTimeout)
+	}
+	if config.ShutdownTimeout != 9*time.Second {
+		t.Fatalf("Expected the default shutdown timeout, got %s", config.ShutdownTimeout)
+	}
+}
+
+func TestLoadConfigDatabaseDefaults(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DATABASE_BACKEND", "")
+	t.Setenv("DATABASE_ID", "")
+	config, err := LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.DatabaseBackend != "memory" {
+		t.Fatalf("Expected the memory backend without DATABASE_ID, got %s", config.DatabaseBackend)
+	}
+
+	t.Setenv("DATABASE_ID", "projects/p/databases/d")
+	config, err = LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.DatabaseBackend != "firestore" || config.DatabaseSource != "projects/p/databases/d" {
+		t.Fatalf("Expected the firestore backend from DATABASE_ID, got %s %s", config.DatabaseBackend, config.DatabaseSource)
+	}
+}
+
+func TestLoadConfigRejectsInvalidValues(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	if _, err := LoadConfig([]string{"-sink-capacity", "many"}); err == nil {
+		t.Fatalf("Expected an error for an invalid capacity")
+	}
+}
@@ -0,0 +1,85 @@
+package configurations
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column extracts one typed field from an event, for sinks with a tabular
+// schema. Path is a metadata field (source_id, source_name, received_at,
+// loaded_at, event) or "event." followed by a JSON path into the payload.
+type Column struct {
+	Name string `json:"name" firestore:"name"`
+	Path string `json:"path" firestore:"path"`
+	Type string `json:"type" firestore:"type"` // string, int64, float64, bool or timestamp
+}
+
+var ColumnTypes = []string{"string", "int64", "float64", "bool", "timestamp"}
+
+var metadataColumnPaths = []string{"source_id", "source_name", "received_at", "loaded_at", "event"}
+
+func (s Sink) paramIsOptionalColumns(key string) error {
+	val, ok := s.Config[key]
+	if !ok {
+		return nil
+	}
+	list, ok := val.([]interface{})
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("Parameter %s must be a non empty list of columns.", key)
+	}
+
+	names := map[string]bool{}
+	for i, item := range list {
+		column, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Column %d of %s must be an object.", i, key)
+		}
+		for _, field := range []string{"name", "path", "type"} {
+			if _, ok := column[field].(string); !ok {
+				return fmt.Errorf("Column %d of %s must have a string '%s'.", i, key, field)
+			}
+		}
+		name := column["name"].(string)
+		if names[name] {
+			return fmt.Errorf("Duplicate column name '%s' in %s.", name, key)
+		}
+		names[name] = true
+		if !isColumnPath(column["path"].(string)) {
+			return fmt.Errorf("Column '%s' has an invalid path. Use one of %v or event.<json path>.", name, metadataColumnPaths)
+		}
+		if !contains(ColumnTypes, column["type"].(string)) {
+			return fmt.Errorf("Column '%s' must have a type in %v.", name, ColumnTypes)
+		}
+	}
+	return nil
+}
+
+func isColumnPath(path string) bool {
+	return contains(metadataColumnPaths, path) || (strings.HasPrefix(path, "event.") && len(path) > len("event."))
+}
+
+func contains(list []string, val string) bool {
+	for _, item := range list {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
+
+// ColumnsParam returns the columns at key, or fallback if they are not set
+func (s Sink) ColumnsParam(key string, fallback []Column) []Column {
+	list, ok := s.Config[key].([]interface{})
+	if !ok {
+		return fallback
+	}
+	columns := make([]Column, 0, len(list))
+	for _, item := range list {
+		column, _ := item.(map[string]interface{})
+		name, _ := column["name"].(string)
+		path, _ := column["path"].(string)
+		t, _ := column["type"].(string)
+		columns = append(columns, Column{Name: name, Path: path, Type: t})
+	}
+	return columns
+}
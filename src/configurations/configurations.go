@@ -2,15 +2,19 @@ package configurations
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 type Configuration struct {
 	ID        string `json:"id" firestore:"id"` // "" means unindentified configuration
+	Version int64 `json:"version" firestore:"version"` // Set by the database, incremented by every update
 	UseKey bool `json:"use_key" firestore:"use_key"`
 	KeyHash string `json:"-" firestore:"key_hash"`
 	Name string `json:"name" firestore:"name"`
 	Sink Sink `json:"sink" firestore:"sink"`
+	Labels map[string]string `json:"labels,omitempty" firestore:"labels,omitempty"` // For grouping and filtering configurations
+	Disabled bool `json:"disabled" firestore:"disabled"` // Disabled configurations don't accept webhooks
 	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" firestore:"updated_at"`
 }
@@ -35,6 +39,20 @@ func (c *Configuration) SetKeyHash(keyHash string){
 	c.KeyHash = keyHash
 }
 
+// ValidateLabels checks labels can be used as list filters, which are
+// written key:value
+func ValidateLabels(labels map[string]string) error {
+	for key := range labels {
+		if key == "" {
+			return fmt.Errorf("Label keys can't be empty")
+		}
+		if strings.Contains(key, ":") {
+			return fmt.Errorf("Label key %s can't contain ':'", key)
+		}
+	}
+	return nil
+}
+
 
 type Sink struct {
 	Type string `json:"type" firestore:"type"`
@@ -59,45 +77,96 @@ func (s Sink) Validate() error{
 
 		1. The type attribute needs to match a supported Sink Type
 		2. The Config attribute must be valid for for the chosen sink type
+
+		Supported types and their parameters come from the registered
+		SinkSchemas (see schema.go).
 	*/
 
-	switch s.Type {
-	case "jsonl":
-		err := s.paramIsString("file_path")
-		if err != nil {
-			return err
-		}
+	schema, ok := LookupSinkSchema(s.Type)
+	if !ok {
+		return fmt.Errorf("%s is not a supported sink type.", s.Type)
+	}
 
-	case "bigquery":
-		err := s.paramIsString("project")
-		if err != nil {
-			return err
-		}
-		err = s.paramIsString("dataset")
+	for _, param := range schema.Params {
+		err := s.validateParam(param)
 		if err != nil {
 			return err
 		}
-		err = s.paramIsString("table")
-		if err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("%s is not a supported sink type.", s.Type)
 	}
 
+	if schema.Validate != nil {
+		return schema.Validate(s)
+	}
 	return nil
 }
 
-func (s Sink) paramIsString(key string) error {
-	
-	val, ok := s.Config[key]
+// WithDefaults returns a copy of the sink with the schema defaults filled in
+// for every parameter that isn't set.
+func (s Sink) WithDefaults() Sink {
+	config := make(map[string]interface{}, len(s.Config))
+	for key, value := range s.Config {
+		config[key] = value
+	}
+	schema, _ := LookupSinkSchema(s.Type)
+	for _, param := range schema.Params {
+		if _, ok := config[param.Name]; !ok && param.Default != nil {
+			config[param.Name] = param.Default
+		}
+	}
+	return Sink{Type: s.Type, Config: config}
+}
+
+// Accessors for sink parameters. They assume the sink has been validated,
+// and return the fallback when the parameter is not set.
+func (s Sink) StringParam(key string, fallback string) string {
+	val, ok := s.Config[key].(string)
 	if !ok {
-		return fmt.Errorf("Missing required parameter '%s'.", key)
+		return fallback
 	}
-	_, ok = val.(string)
+	return val
+}
+
+func (s Sink) StringMapParam(key string) map[string]string {
+	result := map[string]string{}
+	m, _ := s.Config[key].(map[string]interface{})
+	for k, v := range m {
+		if str, ok := v.(string); ok {
+			result[k] = str
+		}
+	}
+	return result
+}
+
+func (s Sink) BoolParam(key string, fallback bool) bool {
+	val, ok := s.Config[key].(bool)
 	if !ok {
-		return fmt.Errorf("Parameter %s must be a string.", key)
+		return fallback
+	}
+	return val
+}
+
+func (s Sink) IntParam(key string, fallback int) int {
+	val, ok := toNumber(s.Config[key])
+	if !ok {
+		return fallback
+	}
+	return int(val)
+}
+
+// Numbers come back as float64 from JSON and as int64 from Firestore
+func toNumber(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
 	}
-	return nil
-	
 }
@@ -0,0 +1,224 @@
+package configurations
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Sink schemas
+/*
+	Every sink type registers a SinkSchema describing its parameters. The
+	schema drives Sink.Validate and the defaults applied by WithDefaults, and
+	is served as is by GET /sink-types.
+
+	Sink implementations register their schema together with their factory
+	(see sink.Register), so a new sink type doesn't need changes here.
+*/
+type ParamType string
+
+const (
+	ParamString    ParamType = "string"
+	ParamNumber    ParamType = "number"
+	ParamInteger   ParamType = "integer"
+	ParamBool      ParamType = "bool"
+	ParamStringMap ParamType = "string_map" // Object of string values
+	ParamColumns   ParamType = "columns"    // List of Column objects
+)
+
+type Param struct {
+	Name        string      `json:"name"`
+	Type        ParamType   `json:"type"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default,omitempty"`
+	Options     []string    `json:"options,omitempty"` // Allowed values of a string parameter
+	Positive    bool        `json:"positive,omitempty"` // A number or integer parameter must be > 0
+	Description string      `json:"description,omitempty"`
+}
+
+type SinkSchema struct {
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Params      []Param `json:"params"`
+
+	// Validate runs after every parameter has been checked against its
+	// type, for rules that involve several parameters. Optional.
+	Validate func(Sink) error `json:"-"`
+}
+
+var (
+	schemasMu   sync.RWMutex
+	sinkSchemas = map[string]SinkSchema{}
+)
+
+// RegisterSinkSchema makes a sink type available. It panics if the type is
+// already registered, since that can only be a programming error.
+func RegisterSinkSchema(schema SinkSchema) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	if _, ok := sinkSchemas[schema.Type]; ok {
+		panic(fmt.Sprintf("Sink type %s registered twice", schema.Type))
+	}
+	sinkSchemas[schema.Type] = schema
+}
+
+func LookupSinkSchema(t string) (SinkSchema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	schema, ok := sinkSchemas[t]
+	return schema, ok
+}
+
+// SinkSchemas lists the registered sink types, sorted by type
+func SinkSchemas() []SinkSchema {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	schemas := make([]SinkSchema, 0, len(sinkSchemas))
+	for _, schema := range sinkSchemas {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Type < schemas[j].Type })
+	return schemas
+}
+
+func (s Sink) validateParam(param Param) error {
+	if _, ok := s.Config[param.Name]; !ok {
+		if param.Required {
+			return fmt.Errorf("Missing required parameter '%s'.", param.Name)
+		}
+		return nil
+	}
+
+	switch param.Type {
+	case ParamString:
+		if len(param.Options) > 0 {
+			return s.paramIsOneOf(param.Name, param.Options...)
+		}
+		return s.paramIsString(param.Name)
+	case ParamNumber:
+		err := s.paramIsOptionalNumber(param.Name)
+		if err != nil || !param.Positive {
+			return err
+		}
+		return s.paramIsPositive(param.Name)
+	case ParamInteger:
+		err := s.paramIsOptionalInteger(param.Name)
+		if err != nil || !param.Positive {
+			return err
+		}
+		return s.paramIsPositive(param.Name)
+	case ParamBool:
+		return s.paramIsOptionalBool(param.Name)
+	case ParamStringMap:
+		return s.paramIsOptionalStringMap(param.Name)
+	case ParamColumns:
+		return s.paramIsOptionalColumns(param.Name)
+	default:
+		return fmt.Errorf("Parameter %s has unknown type %s.", param.Name, param.Type)
+	}
+}
+
+func (s Sink) paramIsString(key string) error {
+
+	val, ok := s.Config[key]
+	if !ok {
+		return fmt.Errorf("Missing required parameter '%s'.", key)
+	}
+	_, ok = val.(string)
+	if !ok {
+		return fmt.Errorf("Parameter %s must be a string.", key)
+	}
+	return nil
+
+}
+
+func (s Sink) paramIsOptionalString(key string) error {
+	if _, ok := s.Config[key]; !ok {
+		return nil
+	}
+	return s.paramIsString(key)
+}
+
+func (s Sink) paramIsOptionalBool(key string) error {
+	val, ok := s.Config[key]
+	if !ok {
+		return nil
+	}
+	_, ok = val.(bool)
+	if !ok {
+		return fmt.Errorf("Parameter %s must be a boolean.", key)
+	}
+	return nil
+}
+
+func (s Sink) paramIsOptionalNumber(key string) error {
+	val, ok := s.Config[key]
+	if !ok {
+		return nil
+	}
+	_, ok = toNumber(val)
+	if !ok {
+		return fmt.Errorf("Parameter %s must be a number.", key)
+	}
+	return nil
+}
+
+func (s Sink) paramIsOptionalStringMap(key string) error {
+	val, ok := s.Config[key]
+	if !ok {
+		return nil
+	}
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Parameter %s must be an object.", key)
+	}
+	for k, v := range m {
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("Value of %s in %s must be a string.", k, key)
+		}
+	}
+	return nil
+}
+
+// paramIsOneOf checks that an optional string parameter is one of the allowed options
+func (s Sink) paramIsOneOf(key string, options ...string) error {
+	val, ok := s.Config[key]
+	if !ok {
+		return nil
+	}
+	str, ok := val.(string)
+	if !ok {
+		return fmt.Errorf("Parameter %s must be a string.", key)
+	}
+	for _, option := range options {
+		if str == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("Parameter %s must be one of %v.", key, options)
+}
+
+// paramIsPositive assumes the parameter was checked to be a number
+func (s Sink) paramIsPositive(key string) error {
+	n, _ := toNumber(s.Config[key])
+	if n <= 0 {
+		return fmt.Errorf("Parameter %s must be greater than 0.", key)
+	}
+	return nil
+}
+
+func (s Sink) paramIsOptionalInteger(key string) error {
+	val, ok := s.Config[key]
+	if !ok {
+		return nil
+	}
+	n, ok := toNumber(val)
+	if !ok || n != math.Trunc(n) {
+		return fmt.Errorf("Parameter %s must be an integer.", key)
+	}
+	return nil
+}
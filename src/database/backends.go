@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Database backends
+/*
+	Backends register an opener under their name, so the service can pick
+	one at startup. The source is backend specific: a Firestore database id,
+	a connection URL, a file path, ...
+*/
+type Opener func(ctx context.Context, source string) (Database, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Opener{}
+)
+
+func init() {
+	RegisterBackend("memory", func(ctx context.Context, source string) (Database, error) {
+		return NewInMemoryDB(), nil
+	})
+	RegisterBackend("firestore", func(ctx context.Context, source string) (Database, error) {
+		return NewFirestoreDatabase(ctx, source)
+	})
+	RegisterBackend("postgres", func(ctx context.Context, source string) (Database, error) {
+		return NewPostgresDatabase(ctx, source)
+	})
+	RegisterBackend("bolt", func(ctx context.Context, source string) (Database, error) {
+		return NewBoltDatabase(source)
+	})
+}
+
+// RegisterBackend makes a backend available to Open. It panics if the name
+// is already taken.
+func RegisterBackend(name string, open Opener) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("database backend %s registered twice", name))
+	}
+	backends[name] = open
+}
+
+// Backends returns the names of the registered backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens a database with the named backend
+func Open(ctx context.Context, backend string, source string) (Database, error) {
+	backendsMu.RLock()
+	open, ok := backends[backend]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("Unknown database backend %s, expected one of %v", backend, Backends())
+	}
+	db, err := open(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s database: %v", backend, err)
+	}
+	return db, nil
+}
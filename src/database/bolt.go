@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// Embedded Database
+/*
+	Stores configurations in a single bbolt file, for single node and
+	development deployments that should survive restarts without an external
+	database. Pure Go, so it builds with CGO_ENABLED=0.
+
+	Configurations are JSON documents keyed by id in the configurations
+	bucket. bbolt locks the file, so only one process can open it at a time.
+	Names that are set are checked for duplicates by scanning the bucket
+	within the write transaction.
+*/
+var boltConfigurations = []byte("configurations")
+
+type boltDatabase struct {
+	db *bolt.DB
+}
+
+// boltRecord is the stored form of a configuration. conf.Configuration hides
+// the key hash from JSON, so it can't be stored as is.
+type boltRecord struct {
+	ID        string            `json:"id"`
+	Version   int64             `json:"version"`
+	UseKey    bool              `json:"use_key"`
+	KeyHash   string            `json:"key_hash"`
+	Name      string            `json:"name"`
+	Sink      conf.Sink         `json:"sink"`
+	Labels    map[string]string `json:"labels"`
+	Disabled  bool              `json:"disabled"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func NewBoltDatabase(path string) (Database, error) {
+	if path == "" {
+		return nil, errors.New("Database file path is required")
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Error opening database file: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltConfigurations)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error creating configurations bucket: %v", err)
+	}
+	return &boltDatabase{db: db}, nil
+}
+
+func encodeBoltRecord(config conf.Configuration) ([]byte, error) {
+	return json.Marshal(boltRecord(config))
+}
+
+func decodeBoltRecord(data []byte) (conf.Configuration, error) {
+	var record boltRecord
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		return conf.Configuration{}, fmt.Errorf("Error decoding configuration: %v", err)
+	}
+	return conf.Configuration(record), nil
+}
+
+func (db *boltDatabase) InsertConfig(ctx context.Context, config conf.Configuration) (conf.Configuration, error) {
+	if config.ID != "" {
+		return conf.Configuration{}, errors.New("Can't insert identified config")
+	}
+	config.SetID(uuid.NewString())
+	config.Version = 1
+
+	data, err := encodeBoltRecord(config)
+	if err != nil {
+		return conf.Configuration{}, err
+	}
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltConfigurations)
+		err := checkBoltName(bucket, config)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(config.ID), data)
+	})
+	if err != nil {
+		return conf.Configuration{}, err
+	}
+	return config, nil
+}
+
+// checkBoltName fails if a configuration other than config has its name
+func checkBoltName(bucket *bolt.Bucket, config conf.Configuration) error {
+	if config.Name == "" {
+		return nil
+	}
+	return bucket.ForEach(func(key []byte, value []byte) error {
+		if string(key) == config.ID {
+			return nil
+		}
+		stored, err := decodeBoltRecord(value)
+		if err != nil {
+			return err
+		}
+		if stored.Name == config.Name {
+			return duplicateName(config.Name)
+		}
+		return nil
+	})
+}
+
+// ListConfigs reads the whole bucket and filters in memory. Meant for a
+// modest number of configurations, like the rest of this backend.
+func (db *boltDatabase) ListConfigs(ctx context.Context, options ListOptions) (ConfigPage, error) {
+	configs := []conf.Configuration{}
+	err := db.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltConfigurations).ForEach(func(key []byte, value []byte) error {
+			config, err := decodeBoltRecord(value)
+			if err != nil {
+				return err
+			}
+			configs = append(configs, config)
+			return nil
+		})
+	})
+	if err != nil {
+		return ConfigPage{}, err
+	}
+	return listInMemory(configs, options)
+}
+
+func (db *boltDatabase) GetConfigByID(ctx context.Context, id string) (conf.Configuration, error) {
+	if id == "" {
+		return conf.Configuration{}, errors.New("Config ID is required")
+	}
+
+	var config conf.Configuration
+	err := db.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltConfigurations).Get([]byte(id))
+		if data == nil {
+			return notFound(id)
+		}
+		var err error
+		config, err = decodeBoltRecord(data)
+		return err
+	})
+	return config, err
+}
+
+func (db *boltDatabase) UpdateConfig(ctx context.Context, config conf.Configuration) (conf.Configuration, error) {
+	if config.ID == "" {
+		return conf.Configuration{}, errors.New("Config ID is required")
+	}
+
+	var result conf.Configuration
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltConfigurations)
+		data := bucket.Get([]byte(config.ID))
+		if data == nil {
+			return notFound(config.ID)
+		}
+		stored, err := decodeBoltRecord(data)
+		if err != nil {
+			return err
+		}
+		err = checkVersion(config.ID, config.Version, stored.Version)
+		if err != nil {
+			return err
+		}
+		err = checkBoltName(bucket, config)
+		if err != nil {
+			return err
+		}
+
+		result = nextVersion(config, stored.Version)
+		data, err = encodeBoltRecord(result)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(config.ID), data)
+	})
+	if err != nil {
+		return conf.Configuration{}, err
+	}
+	return result, nil
+}
+
+func (db *boltDatabase) DeleteConfig(ctx context.Context, id string, version int64) (conf.Configuration, error) {
+	if id == "" {
+		return conf.Configuration{}, errors.New("Config ID is required")
+	}
+
+	var config conf.Configuration
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltConfigurations)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return notFound(id)
+		}
+		var err error
+		config, err = decodeBoltRecord(data)
+		if err != nil {
+			return err
+		}
+		err = checkVersion(id, version, config.Version)
+		if err != nil {
+			return err
+		}
+		return bucket.Delete([]byte(id))
+	})
+	if err != nil {
+		return conf.Configuration{}, err
+	}
+	return config, nil
+}
+
+func (db *boltDatabase) Close() error {
+	return db.db.Close()
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func TestBoltDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configurations.db")
+	db, err := NewBoltDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	config := conf.NewConfiguration("orders", conf.Sink{Type: "stdout", Config: map[string]interface{}{"format": "json"}}, true)
+	config.SetKeyHash("hash")
+	inserted, err := db.InsertConfig(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	inserted.Name = "renamed"
+	if _, err := db.UpdateConfig(context.Background(), inserted); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if err := db.(*boltDatabase).Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+
+	// Configurations survive reopening the file, key hash included
+	db, err = NewBoltDatabase(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.(*boltDatabase).Close()
+
+	got, err := db.GetConfigByID(context.Background(), inserted.ID)
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if got.Name != "renamed" || got.KeyHash != "hash" || got.Sink.Config["format"] != "json" {
+		t.Fatalf("Unexpected config %+v", got)
+	}
+	if !got.CreatedAt.Equal(config.CreatedAt) {
+		t.Fatalf("Expected created_at %v, got %v", config.CreatedAt, got.CreatedAt)
+	}
+
+	page, err := db.ListConfigs(context.Background(), ListOptions{})
+	if err != nil || len(page.Configs) != 1 {
+		t.Fatalf("Expected 1 configuration, got %v (%v)", page.Configs, err)
+	}
+	if _, err := db.DeleteConfig(context.Background(), inserted.ID, AnyVersion); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	if _, err := db.GetConfigByID(context.Background(), inserted.ID); err == nil {
+		t.Fatalf("Expected deleted config to be gone")
+	}
+	if _, err := db.UpdateConfig(context.Background(), inserted); err == nil {
+		t.Fatalf("Expected updating a deleted config to fail")
+	}
+}
+
+func TestBoltListConfigs(t *testing.T) {
+	db, err := NewBoltDatabase(filepath.Join(t.TempDir(), "configurations.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.(*boltDatabase).Close()
+
+	testListConfigs(t, db)
+}
+
+func TestBoltConfigVersions(t *testing.T) {
+	db, err := NewBoltDatabase(filepath.Join(t.TempDir(), "configurations.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.(*boltDatabase).Close()
+
+	testConfigVersions(t, db)
+}
+
+func TestBoltUniqueNames(t *testing.T) {
+	db, err := NewBoltDatabase(filepath.Join(t.TempDir(), "configurations.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.(*boltDatabase).Close()
+
+	testUniqueNames(t, db)
+}
@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// Configuration cache
+/*
+	Wraps a Database to serve GetConfigByID, called for every ingested
+	webhook, from memory.
+
+	- Entries expire after TTL, which bounds how stale a configuration
+	  changed by another instance can get.
+	- Apply invalidates entries as configuration changes are pushed by a
+	  ConfigWatcher, so changes usually show up well before the TTL.
+	- Unknown ids are remembered for NegativeTTL, so scans over random ids
+	  don't each cost a database round trip.
+	- Once MaxEntries is reached, storing an entry evicts an expired one,
+	  then the least recently used negative one, then the least recently
+	  used configuration.
+	- Writes through the cache invalidate the written configuration.
+	- A fetch that was in flight when its configuration was invalidated
+	  isn't cached, since it may have read the configuration from before
+	  the change.
+
+	Other errors are never cached.
+*/
+type ConfigCacheOptions struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration // 0 disables negative caching
+	MaxEntries  int           // 0 for no limit
+}
+
+type ConfigCache struct {
+	Database
+	options ConfigCacheOptions
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	stats   cacheCounters
+	uses    uint64 // Orders entries by last use
+
+	// Per id, while fetches are in flight
+	fetching    map[string]int
+	generations map[string]uint64
+}
+
+type cacheEntry struct {
+	config    conf.Configuration
+	err       error // Set for negative entries
+	expiresAt time.Time
+	lastUsed  uint64
+}
+
+type cacheCounters struct {
+	hits          int64
+	negativeHits  int64
+	misses        int64
+	invalidations int64
+}
+
+type ConfigCacheStats struct {
+	Entries       int     `json:"entries"`
+	Hits          int64   `json:"hits"`
+	NegativeHits  int64   `json:"negative_hits"`
+	Misses        int64   `json:"misses"`
+	Invalidations int64   `json:"invalidations"`
+	HitRatio      float64 `json:"hit_ratio"`
+}
+
+func NewConfigCache(db Database, options ConfigCacheOptions) *ConfigCache {
+	return &ConfigCache{
+		Database: db,
+		options:  options,
+		entries:  map[string]cacheEntry{},
+
+		fetching:    map[string]int{},
+		generations: map[string]uint64{},
+	}
+}
+
+func (c *ConfigCache) GetConfigByID(ctx context.Context, id string) (conf.Configuration, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[id]
+	if ok && now.Before(entry.expiresAt) {
+		if entry.err != nil {
+			c.stats.negativeHits++
+		} else {
+			c.stats.hits++
+		}
+		c.uses++
+		entry.lastUsed = c.uses
+		c.entries[id] = entry
+		c.mu.Unlock()
+		return entry.config, entry.err
+	}
+	c.stats.misses++
+	generation := c.generations[id]
+	c.fetching[id]++
+	c.mu.Unlock()
+
+	config, err := c.Database.GetConfigByID(ctx, id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[id] == generation {
+		switch {
+		case err == nil:
+			c.store(id, cacheEntry{config: config, expiresAt: now.Add(c.options.TTL)})
+		case errors.Is(err, ErrNotFound) && c.options.NegativeTTL > 0:
+			c.store(id, cacheEntry{err: err, expiresAt: now.Add(c.options.NegativeTTL)})
+		}
+	}
+	c.fetching[id]--
+	if c.fetching[id] == 0 {
+		delete(c.fetching, id)
+		delete(c.generations, id)
+	}
+	return config, err
+}
+
+// store caches an entry, evicting another one if the cache is full. Must
+// hold the lock.
+func (c *ConfigCache) store(id string, entry cacheEntry) {
+	_, replacing := c.entries[id]
+	if !replacing && c.options.MaxEntries > 0 && len(c.entries) >= c.options.MaxEntries {
+		c.removeExpired()
+		if len(c.entries) >= c.options.MaxEntries {
+			c.evict()
+		}
+	}
+	c.uses++
+	entry.lastUsed = c.uses
+	c.entries[id] = entry
+}
+
+// evict drops the least recently used negative entry, or the least recently
+// used entry if there are no negative ones. Must hold the lock.
+func (c *ConfigCache) evict() {
+	var victim string
+	var oldest cacheEntry
+	found := false
+	for id, entry := range c.entries {
+		if !found || evictsBefore(entry, oldest) {
+			victim, oldest, found = id, entry, true
+		}
+	}
+	if found {
+		delete(c.entries, victim)
+	}
+}
+
+func evictsBefore(a cacheEntry, b cacheEntry) bool {
+	if (a.err != nil) != (b.err != nil) {
+		return a.err != nil
+	}
+	return a.lastUsed < b.lastUsed
+}
+
+// removeExpired drops expired entries. Must hold the lock.
+func (c *ConfigCache) removeExpired() {
+	now := time.Now()
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// Invalidate drops the cached entry of a configuration
+func (c *ConfigCache) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.fetching[id] > 0 {
+		c.generations[id]++
+	}
+	if _, ok := c.entries[id]; ok {
+		delete(c.entries, id)
+		c.stats.invalidations++
+	}
+}
+
+// Apply invalidates the configuration of a change pushed by a ConfigWatcher
+func (c *ConfigCache) Apply(change ConfigChange) {
+	c.Invalidate(change.Config.ID)
+}
+
+func (c *ConfigCache) InsertConfig(ctx context.Context, config conf.Configuration) (conf.Configuration, error) {
+	result, err := c.Database.InsertConfig(ctx, config)
+	if err == nil {
+		c.Invalidate(result.ID)
+	}
+	return result, err
+}
+
+func (c *ConfigCache) UpdateConfig(ctx context.Context, config conf.Configuration) (conf.Configuration, error) {
+	result, err := c.Database.UpdateConfig(ctx, config)
+	c.Invalidate(config.ID)
+	return result, err
+}
+
+func (c *ConfigCache) DeleteConfig(ctx context.Context, id string, version int64) (conf.Configuration, error) {
+	result, err := c.Database.DeleteConfig(ctx, id, version)
+	c.Invalidate(id)
+	return result, err
+}
+
+func (c *ConfigCache) Stats() ConfigCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := ConfigCacheStats{
+		Entries:       len(c.entries),
+		Hits:          c.stats.hits,
+		NegativeHits:  c.stats.negativeHits,
+		Misses:        c.stats.misses,
+		Invalidations: c.stats.invalidations,
+	}
+	lookups := stats.Hits + stats.NegativeHits + stats.Misses
+	if lookups > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.NegativeHits) / float64(lookups)
+	}
+	return stats
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// countingDatabase counts the lookups reaching the database
+type countingDatabase struct {
+	Database
+	gets int
+}
+
+func (db *countingDatabase) GetConfigByID(ctx context.Context, id string) (conf.Configuration, error) {
+	db.gets++
+	return db.Database.GetConfigByID(ctx, id)
+}
+
+func TestConfigCache(t *testing.T) {
+	backend := &countingDatabase{Database: NewInMemoryDB()}
+	cache := NewConfigCache(backend, ConfigCacheOptions{TTL: time.Hour, NegativeTTL: time.Hour})
+
+	config, err := cache.InsertConfig(context.Background(), conf.NewConfiguration("cached", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+
+	// Only the first lookup reaches the database
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetConfigByID(context.Background(), config.ID); err != nil {
+			t.Fatalf("Failed to get config: %v", err)
+		}
+	}
+	if backend.gets != 1 {
+		t.Fatalf("Expected 1 database lookup, got %d", backend.gets)
+	}
+
+	// Unknown ids are cached too
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetConfigByID(context.Background(), "unknown"); err == nil {
+			t.Fatalf("Expected an error for an unknown id")
+		}
+	}
+	if backend.gets != 2 {
+		t.Fatalf("Expected 2 database lookups, got %d", backend.gets)
+	}
+
+	// Updates and pushed changes invalidate the entry
+	config.Name = "renamed"
+	if _, err := cache.UpdateConfig(context.Background(), config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	cached, _ := cache.GetConfigByID(context.Background(), config.ID)
+	if cached.Name != "renamed" {
+		t.Fatalf("Expected the updated config, got %s", cached.Name)
+	}
+	cache.Apply(ConfigChange{Kind: ConfigRemoved, Config: config})
+	cache.GetConfigByID(context.Background(), config.ID)
+	if backend.gets != 4 {
+		t.Fatalf("Expected 4 database lookups, got %d", backend.gets)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.NegativeHits != 2 || stats.Misses != 4 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+	if stats.HitRatio != 0.5 {
+		t.Fatalf("Expected a hit ratio of 0.5, got %v", stats.HitRatio)
+	}
+}
+
+func TestConfigCacheExpiry(t *testing.T) {
+	backend := &countingDatabase{Database: NewInMemoryDB()}
+	cache := NewConfigCache(backend, ConfigCacheOptions{TTL: time.Millisecond})
+
+	config, _ := backend.InsertConfig(context.Background(), conf.NewConfiguration("cached", conf.Sink{Type: "stdout"}, false))
+	cache.GetConfigByID(context.Background(), config.ID)
+	time.Sleep(5 * time.Millisecond)
+	cache.GetConfigByID(context.Background(), config.ID)
+
+	// Negative caching is disabled
+	cache.GetConfigByID(context.Background(), "unknown")
+	cache.GetConfigByID(context.Background(), "unknown")
+
+	if backend.gets != 4 {
+		t.Fatalf("Expected 4 database lookups, got %d", backend.gets)
+	}
+}
+
+// blockingDatabase holds lookups until released
+type blockingDatabase struct {
+	Database
+	started chan struct{}
+	release chan struct{}
+}
+
+func (db *blockingDatabase) GetConfigByID(ctx context.Context, id string) (conf.Configuration, error) {
+	config, err := db.Database.GetConfigByID(ctx, id)
+	db.started <- struct{}{}
+	<-db.release
+	return config, err
+}
+
+func TestConfigCacheInvalidatedDuringFetch(t *testing.T) {
+	ctx := context.Background()
+	memory := NewInMemoryDB()
+	config, err := memory.InsertConfig(ctx, conf.NewConfiguration("racing", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	backend := &blockingDatabase{Database: memory, started: make(chan struct{}), release: make(chan struct{})}
+	cache := NewConfigCache(backend, ConfigCacheOptions{TTL: time.Hour})
+
+	// The fetch reads the old configuration, then an update lands before it returns
+	done := make(chan struct{})
+	go func() {
+		cache.GetConfigByID(ctx, config.ID)
+		close(done)
+	}()
+	<-backend.started
+	config.Name = "renamed"
+	if _, err := memory.UpdateConfig(ctx, config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	cache.Apply(ConfigChange{Kind: ConfigModified, Config: config})
+	close(backend.release)
+	<-done
+
+	go func() { <-backend.started }()
+	cached, err := cache.GetConfigByID(ctx, config.ID)
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if cached.Name != "renamed" {
+		t.Fatalf("Expected the stale fetch to be dropped, got %s", cached.Name)
+	}
+}
+
+func TestConfigCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingDatabase{Database: NewInMemoryDB()}
+	cache := NewConfigCache(backend, ConfigCacheOptions{TTL: time.Hour, NegativeTTL: time.Hour, MaxEntries: 3})
+
+	var ids []string
+	for _, name := range []string{"first", "second"} {
+		config, err := backend.InsertConfig(ctx, conf.NewConfiguration(name, conf.Sink{Type: "stdout"}, false))
+		if err != nil {
+			t.Fatalf("Failed to insert config: %v", err)
+		}
+		ids = append(ids, config.ID)
+	}
+
+	// Misses fill the cache before the first configuration is looked up
+	for _, id := range []string{"unknown-1", "unknown-2", "unknown-3"} {
+		cache.GetConfigByID(ctx, id)
+	}
+	if _, err := cache.GetConfigByID(ctx, ids[0]); err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if _, err := cache.GetConfigByID(ctx, ids[1]); err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if backend.gets != 5 {
+		t.Fatalf("Expected 5 database lookups, got %d", backend.gets)
+	}
+
+	// Both configurations are cached, the oldest negative entries were evicted
+	cache.GetConfigByID(ctx, ids[0])
+	cache.GetConfigByID(ctx, ids[1])
+	cache.GetConfigByID(ctx, "unknown-3")
+	if backend.gets != 5 {
+		t.Fatalf("Expected 5 database lookups, got %d", backend.gets)
+	}
+
+	// Without negative entries left, the least recently used one goes
+	config, err := backend.InsertConfig(ctx, conf.NewConfiguration("third", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	cache.GetConfigByID(ctx, config.ID) // Evicts unknown-3
+	cache.GetConfigByID(ctx, ids[0])    // Makes the second configuration the oldest
+	if backend.gets != 6 {
+		t.Fatalf("Expected 6 database lookups, got %d", backend.gets)
+	}
+	cache.GetConfigByID(ctx, "unknown-4") // Evicts the second configuration
+	cache.GetConfigByID(ctx, ids[0])
+	cache.GetConfigByID(ctx, config.ID)
+	if backend.gets != 7 {
+		t.Fatalf("Expected 7 database lookups, got %d", backend.gets)
+	}
+	cache.GetConfigByID(ctx, ids[1])
+	if backend.gets != 8 {
+		t.Fatalf("Expected 8 database lookups, got %d", backend.gets)
+	}
+	if stats := cache.Stats(); stats.Entries != 3 {
+		t.Fatalf("Expected 3 entries, got %d", stats.Entries)
+	}
+}
@@ -1,8 +1,11 @@
 package database
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -11,14 +14,84 @@ import (
 
 
 type Database interface {
-	InsertConfig(conf.Configuration) (conf.Configuration, error)
-	ListConfigs() ([]conf.Configuration, error)
-	GetConfigByID(string) (conf.Configuration, error) // Returns identified configuration
-	UpdateConfig(conf.Configuration) (conf.Configuration, error)
-	DeleteConfig(string) (conf.Configuration, error)
+	InsertConfig(context.Context, conf.Configuration) (conf.Configuration, error)
+	ListConfigs(context.Context, ListOptions) (ConfigPage, error) // See list.go
+	GetConfigByID(context.Context, string) (conf.Configuration, error) // Returns identified configuration
+	UpdateConfig(context.Context, conf.Configuration) (conf.Configuration, error) // See Versions
+	DeleteConfig(ctx context.Context, id string, version int64) (conf.Configuration, error) // See Versions
+}
+
+// Versions
+/*
+	InsertConfig stores configurations at version 1. UpdateConfig only
+	stores a configuration if its Version is still the stored one, and
+	returns it at the next version with UpdatedAt refreshed. DeleteConfig
+	only deletes the given version. Either fails with ErrVersionMismatch
+	when the configuration was changed in between, so concurrent updates
+	can't silently overwrite each other.
+
+	AnyVersion skips the check.
+*/
+const AnyVersion int64 = -1
+
+// checkVersion fails unless expected is the stored version or AnyVersion
+func checkVersion(id string, expected int64, stored int64) error {
+	if expected != AnyVersion && expected != stored {
+		return fmt.Errorf("Configuration with id %s is at version %d, not %d: %w", id, stored, expected, ErrVersionMismatch)
+	}
+	return nil
+}
+
+// nextVersion is the configuration as stored by an update over version stored
+func nextVersion(config conf.Configuration, stored int64) conf.Configuration {
+	config.Version = stored + 1
+	config.UpdatedAt = updateTime()
+	return config
+}
+
+// updateTime is the UpdatedAt of an update made now. Microseconds are the
+// finest precision every backend keeps.
+func updateTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Errors
+/*
+	Implementations wrap these, so callers can tell them apart with
+	errors.Is whatever the backend.
+*/
+var (
+	ErrNotFound = errors.New("not found") // No configuration with the given id
+	ErrConflict = errors.New("conflicts with a stored configuration") // E.g. a duplicate name
+	ErrVersionMismatch = errors.New("version mismatch") // The configuration was changed since it was read
+)
+
+func notFound(id string) error {
+	return fmt.Errorf("Configuration with id %s %w", id, ErrNotFound)
+}
+
+// Configuration names are optional, but those that are set are unique in
+// every backend
+func duplicateName(name string) error {
+	return fmt.Errorf("Configuration named %s already exists: %w", name, ErrConflict)
+}
+
+// nameTaken reports whether a configuration other than id is named name.
+// Must hold the lock.
+func (db *inMemoryDatabase) nameTaken(name string, id string) bool {
+	if name == "" {
+		return false
+	}
+	for _, config := range db.Confs {
+		if config.Name == name && config.ID != id {
+			return true
+		}
+	}
+	return false
 }
 
 type inMemoryDatabase struct {
+	mu sync.RWMutex
 	Confs map[string]conf.Configuration
 }
 
@@ -28,7 +101,7 @@ func NewInMemoryDB() Database {
 	}
 }
 
-func (db *inMemoryDatabase) InsertConfig(c conf.Configuration) (conf.Configuration, error) {
+func (db *inMemoryDatabase) InsertConfig(ctx context.Context, c conf.Configuration) (conf.Configuration, error) {
 
 	var result conf.Configuration
 
@@ -39,30 +112,42 @@ func (db *inMemoryDatabase) InsertConfig(c conf.Configuration) (conf.Configurati
 
 	result = c
 	result.SetID(uuid.NewString())
+	result.Version = 1
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
+	if db.nameTaken(result.Name, result.ID) {
+		return conf.Configuration{}, duplicateName(result.Name)
+	}
 	db.Confs[result.ID] = result
 	return result, nil
 }
 
-func (db *inMemoryDatabase) ListConfigs() ([]conf.Configuration, error) {
+func (db *inMemoryDatabase) ListConfigs(ctx context.Context, options ListOptions) (ConfigPage, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	var configs []conf.Configuration = []conf.Configuration{}
 	for _, value := range db.Confs {
 		configs = append(configs, value)
 	}
 
-	// This interface, in particular, can't error
-	return configs, nil
+	return listInMemory(configs, options)
 }
 
-func (db *inMemoryDatabase) GetConfigByID(id string) (conf.Configuration, error) {
+func (db *inMemoryDatabase) GetConfigByID(ctx context.Context, id string) (conf.Configuration, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
 	config, ok := db.Confs[id]
 	if !ok {
-		return config, fmt.Errorf("conf.Configuration with id %s not found", id)
+		return config, notFound(id)
 	}
 	return config, nil
 }
 
-func (db *inMemoryDatabase) UpdateConfig(c conf.Configuration) (conf.Configuration, error) {
+func (db *inMemoryDatabase) UpdateConfig(ctx context.Context, c conf.Configuration) (conf.Configuration, error) {
 
 	// The input conf.Configuration must be Identified
 	var result conf.Configuration
@@ -70,21 +155,40 @@ func (db *inMemoryDatabase) UpdateConfig(c conf.Configuration) (conf.Configurati
 		return result, errors.New("Configuration must be identified")
 	}
 
-	_, ok := db.Confs[c.ID]
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	stored, ok := db.Confs[c.ID]
 	if !ok {
-		return result, errors.New("Configuration not found")
+		return result, notFound(c.ID)
+	}
+	err := checkVersion(c.ID, c.Version, stored.Version)
+	if err != nil {
+		return result, err
+	}
+	if db.nameTaken(c.Name, c.ID) {
+		return result, duplicateName(c.Name)
 	}
 
-	db.Confs[c.ID] = c
-	return c, nil
+	result = nextVersion(c, stored.Version)
+	db.Confs[c.ID] = result
+	return result, nil
 }
 
-func (db *inMemoryDatabase) DeleteConfig(id string) (conf.Configuration, error) {
+func (db *inMemoryDatabase) DeleteConfig(ctx context.Context, id string, version int64) (conf.Configuration, error) {
 	var result conf.Configuration
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
 	// Check if configuration exists
 	config, ok := db.Confs[id]
 	if !ok {
-		return result, errors.New(fmt.Sprintf("conf.Configuration with id %s not found", id))
+		return result, notFound(id)
+	}
+	err := checkVersion(id, version, config.Version)
+	if err != nil {
+		return result, err
 	}
 
 	// Delete it
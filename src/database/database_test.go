@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// testConfigVersions checks updates and deletes are conditional on the version
+func testConfigVersions(t *testing.T, db Database) {
+	ctx := context.Background()
+	inserted, err := db.InsertConfig(ctx, conf.NewConfiguration("versioned", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	if inserted.Version != 1 {
+		t.Fatalf("Expected inserted config at version 1, got %d", inserted.Version)
+	}
+
+	// Two writers read version 1, only the first update lands
+	first, second := inserted, inserted
+	first.Name = "first"
+	second.Name = "second"
+	updated, err := db.UpdateConfig(ctx, first)
+	if err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if updated.Version != 2 || !updated.UpdatedAt.After(inserted.UpdatedAt) {
+		t.Fatalf("Expected version 2 and a later updated_at, got %d and %v", updated.Version, updated.UpdatedAt)
+	}
+	if _, err := db.UpdateConfig(ctx, second); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Expected ErrVersionMismatch updating a stale config, got %v", err)
+	}
+	got, err := db.GetConfigByID(ctx, inserted.ID)
+	if err != nil || got.Name != "first" || got.Version != 2 {
+		t.Fatalf("Expected the first update to be stored, got %+v (%v)", got, err)
+	}
+
+	// Deletes check the version too, unless it's AnyVersion
+	if _, err := db.DeleteConfig(ctx, inserted.ID, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("Expected ErrVersionMismatch deleting a stale version, got %v", err)
+	}
+	if _, err := db.DeleteConfig(ctx, inserted.ID, 2); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	if _, err := db.DeleteConfig(ctx, inserted.ID, AnyVersion); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound deleting a deleted config, got %v", err)
+	}
+	if _, err := db.UpdateConfig(ctx, updated); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound updating a deleted config, got %v", err)
+	}
+}
+
+func TestInMemoryConfigVersions(t *testing.T) {
+	testConfigVersions(t, NewInMemoryDB())
+}
+
+// testUniqueNames checks inserts and renames can't duplicate a name
+func testUniqueNames(t *testing.T, db Database) {
+	ctx := context.Background()
+	orders, err := db.InsertConfig(ctx, conf.NewConfiguration("orders", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	billing, err := db.InsertConfig(ctx, conf.NewConfiguration("billing", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+
+	if _, err := db.InsertConfig(ctx, conf.NewConfiguration("orders", conf.Sink{Type: "stdout"}, false)); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict inserting a duplicate name, got %v", err)
+	}
+	billing.Name = "orders"
+	if _, err := db.UpdateConfig(ctx, billing); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict renaming to a taken name, got %v", err)
+	}
+
+	// Keeping its own name, or taking a freed one, is fine
+	if _, err := db.UpdateConfig(ctx, orders); err != nil {
+		t.Fatalf("Failed to update config under its own name: %v", err)
+	}
+	if _, err := db.DeleteConfig(ctx, orders.ID, AnyVersion); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	if _, err := db.UpdateConfig(ctx, billing); err != nil {
+		t.Fatalf("Failed to rename config to a freed name: %v", err)
+	}
+
+	// Names are optional, so any number of configurations can go without one
+	var unnamed []conf.Configuration
+	for i := 0; i < 2; i++ {
+		config, err := db.InsertConfig(ctx, conf.NewConfiguration("", conf.Sink{Type: "stdout"}, false))
+		if err != nil {
+			t.Fatalf("Failed to insert unnamed config: %v", err)
+		}
+		unnamed = append(unnamed, config)
+	}
+	if _, err := db.UpdateConfig(ctx, unnamed[0]); err != nil {
+		t.Fatalf("Failed to update unnamed config: %v", err)
+	}
+}
+
+func TestInMemoryUniqueNames(t *testing.T) {
+	testUniqueNames(t, NewInMemoryDB())
+}
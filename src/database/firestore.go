@@ -40,6 +40,10 @@ func NewFirestoreDatabase(ctx context.Context, databaseID string) (Database, err
 	}, nil
 }
 
+func (db *firestoreDatabase) Close() error {
+	return db.Client.Close()
+}
+
 func parseDatabaseID(id string) (string, string ,error) {
 	parts := strings.Split(id, "/")
 
@@ -50,62 +54,113 @@ func parseDatabaseID(id string) (string, string ,error) {
 	return parts[1], parts[3], nil
 }
 
-func (db *firestoreDatabase) InsertConfig(config conf.Configuration) (conf.Configuration, error) {
+func (db *firestoreDatabase) InsertConfig(ctx context.Context, config conf.Configuration) (conf.Configuration, error) {
 	if config.ID != "" {
 		return conf.Configuration{}, errors.New("Can't insert identified config")
 	}
+	config.Version = 1
 
-	
-	docRef, _, err := db.Client.Collection("configurations").Add(context.Background(), config)
+	docRef := db.Client.Collection("configurations").NewDoc()
+	config.SetID(docRef.ID)
+
+	// Check the name and write in one transaction, so no duplicate lands in between
+	err := db.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		err := db.checkName(tx, config)
+		if err != nil {
+			return err
+		}
+		return tx.Create(docRef, config)
+	})
 	if err != nil {
 		return conf.Configuration{}, err
 	}
 
-	config.SetID(docRef.ID)
+	return config, nil
+}
 
-	// The document is initially written is a null "id" field. We have to rewrite it to set the id
-	_, err = docRef.Set(context.Background(), config)
+// checkName fails if a configuration other than config has its name
+func (db *firestoreDatabase) checkName(tx *firestore.Transaction, config conf.Configuration) error {
+	if config.Name == "" {
+		return nil
+	}
+	query := db.Client.Collection("configurations").Where("name", "==", config.Name).Limit(2)
+	docs, err := tx.Documents(query).GetAll()
 	if err != nil {
-		return config, fmt.Errorf("Failure setting the id in the database: %v", err)
+		return err
 	}
-
-	return config, nil
+	for _, doc := range docs {
+		if doc.Ref.ID != config.ID {
+			return duplicateName(config.Name)
+		}
+	}
+	return nil
 }
 
-func (db *firestoreDatabase) ListConfigs() ([]conf.Configuration, error) {
-	iter := db.Client.Collection("configurations").Documents(context.Background())
+// ListConfigs queries the configurations in order, starting after the cursor,
+// and applies the filters to the results as they stream in. Filtering in
+// the query would need a composite index for every combination of filter
+// and sort field.
+func (db *firestoreDatabase) ListConfigs(ctx context.Context, options ListOptions) (ConfigPage, error) {
+	options, err := options.normalize()
+	if err != nil {
+		return ConfigPage{}, err
+	}
+	cursor, err := options.decodeCursor()
+	if err != nil {
+		return ConfigPage{}, err
+	}
+
+	direction := firestore.Asc
+	if options.Descending {
+		direction = firestore.Desc
+	}
+	query := db.Client.Collection("configurations").
+		OrderBy(options.SortBy, direction).
+		OrderBy(firestore.DocumentID, direction)
+	if cursor != nil {
+		var value interface{} = cursor.Value
+		if options.SortBy != SortByName {
+			value = cursor.cursorTime()
+		}
+		query = query.StartAfter(value, cursor.ID)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
 	var configs []conf.Configuration
 
-	for {
+	for options.Limit == 0 || len(configs) <= options.Limit {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return ConfigPage{}, err
 		}
 
 		var config conf.Configuration
 		if err := doc.DataTo(&config); err != nil {
-			return nil, err
+			return ConfigPage{}, err
 		}
 
-		configs = append(configs, config)
+		if options.matches(config) {
+			configs = append(configs, config)
+		}
 	}
 
-	return configs, nil
+	return options.pageOf(configs), nil
 }
 
-func (db *firestoreDatabase) GetConfigByID(id string) (conf.Configuration, error) {
+func (db *firestoreDatabase) GetConfigByID(ctx context.Context, id string) (conf.Configuration, error) {
 	if id == "" {
 		return conf.Configuration{}, errors.New("Config ID is required")
 	}
 
 	docRef := db.Client.Collection("configurations").Doc(id)
-	doc, err := docRef.Get(context.Background())
+	doc, err := docRef.Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return conf.Configuration{}, fmt.Errorf("conf.Configuration with id %s not found", id)
+			return conf.Configuration{}, notFound(id)
 		}
 		return conf.Configuration{}, err
 	}
@@ -118,54 +173,109 @@ func (db *firestoreDatabase) GetConfigByID(id string) (conf.Configuration, error
 	return config, nil
 }
 
-func (db *firestoreDatabase) UpdateConfig(config conf.Configuration) (conf.Configuration, error) {
+func (db *firestoreDatabase) UpdateConfig(ctx context.Context, config conf.Configuration) (conf.Configuration, error) {
 	if config.ID == "" {
 		return conf.Configuration{}, errors.New("Config ID is required")
 	}
 
 	docRef := db.Client.Collection("configurations").Doc(config.ID)
 
-	// Check if document exist
-	_, err := docRef.Get(context.Background())
-	if err != nil {
-		if status.Code(err) == codes.NotFound {
-			return conf.Configuration{}, fmt.Errorf("conf.Configuration with id %s not found", config.ID)
+	// Check the version and write in one transaction, so no update lands in between
+	var result conf.Configuration
+	err := db.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored, err := getInTransaction(tx, docRef)
+		if err != nil {
+			return err
+		}
+		err = checkVersion(config.ID, config.Version, stored.Version)
+		if err != nil {
+			return err
+		}
+		err = db.checkName(tx, config)
+		if err != nil {
+			return err
 		}
-		return conf.Configuration{}, err
-	}
 
-	// Update
-	_, err = docRef.Set(context.Background(), config)
+		result = nextVersion(config, stored.Version)
+		return tx.Set(docRef, result)
+	})
 	if err != nil {
 		return conf.Configuration{}, err
 	}
 
-	return config, nil
+	return result, nil
 }
 
-func (db *firestoreDatabase) DeleteConfig(id string) (conf.Configuration, error) {
-	if id == "" {
-		return conf.Configuration{}, errors.New("Config ID is required")
-	}
-
-	docRef := db.Client.Collection("configurations").Doc(id)
-	doc, err := docRef.Get(context.Background())
+// getInTransaction reads a configuration within a transaction
+func getInTransaction(tx *firestore.Transaction, docRef *firestore.DocumentRef) (conf.Configuration, error) {
+	var config conf.Configuration
+	doc, err := tx.Get(docRef)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return conf.Configuration{}, fmt.Errorf("conf.Configuration with id %s not found", id)
+			return config, notFound(docRef.ID)
 		}
-		return conf.Configuration{}, err
+		return config, err
 	}
+	err = doc.DataTo(&config)
+	return config, err
+}
 
-	var config conf.Configuration
-	if err := doc.DataTo(&config); err != nil {
-		return conf.Configuration{}, err
+func (db *firestoreDatabase) DeleteConfig(ctx context.Context, id string, version int64) (conf.Configuration, error) {
+	if id == "" {
+		return conf.Configuration{}, errors.New("Config ID is required")
 	}
 
-	if _, err := docRef.Delete(context.Background()); err != nil {
+	docRef := db.Client.Collection("configurations").Doc(id)
+	var config conf.Configuration
+	err := db.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var err error
+		config, err = getInTransaction(tx, docRef)
+		if err != nil {
+			return err
+		}
+		err = checkVersion(id, version, config.Version)
+		if err != nil {
+			return err
+		}
+		return tx.Delete(docRef)
+	})
+	if err != nil {
 		return conf.Configuration{}, err
 	}
 
 	return config, nil
 }
 
+
+// WatchConfigs listens to snapshots of the configurations collection
+func (db *firestoreDatabase) WatchConfigs(ctx context.Context, handle func(ConfigChange)) error {
+	snapshots := db.Client.Collection("configurations").Snapshots(ctx)
+	defer snapshots.Stop()
+
+	for {
+		snapshot, err := snapshots.Next()
+		if err != nil {
+			if status.Code(err) == codes.Canceled || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("Error listening to configuration changes: %v", err)
+		}
+
+		for _, change := range snapshot.Changes {
+			var config conf.Configuration
+			if err := change.Doc.DataTo(&config); err != nil {
+				return fmt.Errorf("Error reading changed configuration %s: %v", change.Doc.Ref.ID, err)
+			}
+			config.SetID(change.Doc.Ref.ID)
+
+			switch change.Kind {
+			case firestore.DocumentAdded:
+				handle(ConfigChange{Kind: ConfigAdded, Config: config})
+			case firestore.DocumentModified:
+				handle(ConfigChange{Kind: ConfigModified, Config: config})
+			case firestore.DocumentRemoved:
+				handle(ConfigChange{Kind: ConfigRemoved, Config: config})
+			}
+		}
+	}
+}
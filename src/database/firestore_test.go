@@ -3,22 +3,27 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"testing"
 
 	conf "github.com/altxtech/webhook-connector/src/configurations"
+	_ "github.com/altxtech/webhook-connector/src/sink" // Registers the sink types
 )
 
-func initFirestoreDB() Database {
-	firestoreDB, err := NewFirestoreDatabase(context.Background(), os.Getenv("PROJECT_ID"), os.Getenv("DATABASE_ID"))
+// Runs against the database in DATABASE_ID (projects/{project}/databases/{name}),
+// or the emulator when FIRESTORE_EMULATOR_HOST is also set
+func initFirestoreDB(t *testing.T) Database {
+	databaseID := os.Getenv("DATABASE_ID")
+	if databaseID == "" {
+		t.Skip("DATABASE_ID is not set")
+	}
+	firestoreDB, err := NewFirestoreDatabase(context.Background(), databaseID)
 	if err != nil {
-		log.Fatal(err)
+		t.Fatal(err)
 	}
 
 	return firestoreDB
 }
-var firestoreDB Database = initFirestoreDB()
 
 func createDummyConfig() (conf.Configuration, error) {
 	var config conf.Configuration
@@ -26,19 +31,20 @@ func createDummyConfig() (conf.Configuration, error) {
 	if err != nil {
 		return config, fmt.Errorf("Failed to create dummySinkConfig: %v", err)
 	}
-	config = conf.NewConfiguration(dummySink)
+	config = conf.NewConfiguration("dummy", dummySink, false)
 
 	return config, nil
 }
 
 func TestFirestoreDB(t *testing.T){
+	firestoreDB := initFirestoreDB(t)
 
 	// Write and delete test
 	dummyConfig, err := createDummyConfig()
 	if err != nil {
 		t.Fatalf("Failed to create dummy config: %v", err)
 	}
-	insertedConfig, err := firestoreDB.InsertConfig(dummyConfig)
+	insertedConfig, err := firestoreDB.InsertConfig(context.Background(), dummyConfig)
 	if err != nil {
 		t.Fatalf("Failure inserting config into db: %v", err)
 	}
@@ -49,7 +55,7 @@ func TestFirestoreDB(t *testing.T){
 	}
 
 	// Try deleting it
-	deletedConfig, err := firestoreDB.DeleteConfig(insertedConfig.ID)
+	deletedConfig, err := firestoreDB.DeleteConfig(context.Background(), insertedConfig.ID, AnyVersion)
 	if err != nil {
 		t.Fatalf("Failure deleting config: %v", err)
 	}
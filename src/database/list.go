@@ -0,0 +1,237 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// Listing configurations
+/*
+	ListConfigs returns the configurations matching every filter set in
+	ListOptions, sorted by SortBy and then by id, one page of at most Limit
+	configurations at a time.
+
+	Pages are keyset based: the cursor of a page holds the sort value and id
+	of its last configuration, and the next page starts right after it.
+	Configurations added or removed between pages don't shift the others, so
+	none are skipped or repeated. A cursor is only valid with the sort order
+	it was issued for.
+*/
+type ListOptions struct {
+	NamePrefix string
+	SinkType   string
+	Labels     map[string]string // Configurations must have every label
+	Disabled   *bool             // nil for both enabled and disabled configurations
+
+	SortBy     string // One of SortFields, defaults to created_at
+	Descending bool
+
+	Limit  int    // 0 for no limit
+	Cursor string // NextCursor of the previous page, "" for the first page
+}
+
+type ConfigPage struct {
+	Configs    []conf.Configuration `json:"configurations"`
+	NextCursor string               `json:"next_cursor,omitempty"` // "" on the last page
+}
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+	SortByName      = "name"
+)
+
+var SortFields = []string{SortByCreatedAt, SortByUpdatedAt, SortByName}
+
+// ErrInvalidListOptions is wrapped by the errors for unknown sort fields,
+// negative limits and malformed cursors
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+type listCursor struct {
+	Sort  string `json:"s"` // Sort field, prefixed with - when descending
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// normalize fills in the default sort and checks the options
+func (o ListOptions) normalize() (ListOptions, error) {
+	if o.SortBy == "" {
+		o.SortBy = SortByCreatedAt
+	}
+	known := false
+	for _, field := range SortFields {
+		known = known || field == o.SortBy
+	}
+	if !known {
+		return o, fmt.Errorf("Unknown sort field %s, expected one of %v: %w", o.SortBy, SortFields, ErrInvalidListOptions)
+	}
+	if o.Limit < 0 {
+		return o, fmt.Errorf("Limit can't be negative: %w", ErrInvalidListOptions)
+	}
+	return o, nil
+}
+
+func (o ListOptions) sortKey() string {
+	if o.Descending {
+		return "-" + o.SortBy
+	}
+	return o.SortBy
+}
+
+// sortValue is the value of the sort field of a configuration, as stored in cursors
+func (o ListOptions) sortValue(config conf.Configuration) string {
+	switch o.SortBy {
+	case SortByName:
+		return config.Name
+	case SortByUpdatedAt:
+		return config.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return config.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+func (o ListOptions) encodeCursor(config conf.Configuration) string {
+	data, _ := json.Marshal(listCursor{Sort: o.sortKey(), Value: o.sortValue(config), ID: config.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the cursor of the options, or nil on the first page
+func (o ListOptions) decodeCursor() (*listCursor, error) {
+	if o.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed cursor: %w", ErrInvalidListOptions)
+	}
+	var cursor listCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.ID == "" {
+		return nil, fmt.Errorf("Malformed cursor: %w", ErrInvalidListOptions)
+	}
+	if cursor.Sort != o.sortKey() {
+		return nil, fmt.Errorf("Cursor was issued for sort %s, not %s: %w", cursor.Sort, o.sortKey(), ErrInvalidListOptions)
+	}
+	if o.SortBy != SortByName {
+		_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed cursor: %w", ErrInvalidListOptions)
+		}
+	}
+	return &cursor, nil
+}
+
+// cursorTime is the sort value of a cursor on a time field
+func (c listCursor) cursorTime() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, c.Value)
+	return t
+}
+
+// matches reports whether a configuration passes the filters
+func (o ListOptions) matches(config conf.Configuration) bool {
+	if !strings.HasPrefix(config.Name, o.NamePrefix) {
+		return false
+	}
+	if o.SinkType != "" && config.Sink.Type != o.SinkType {
+		return false
+	}
+	if o.Disabled != nil && config.Disabled != *o.Disabled {
+		return false
+	}
+	for key, value := range o.Labels {
+		if got, ok := config.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders two configurations by the sort field, then id, ascending
+func (o ListOptions) compare(a conf.Configuration, b conf.Configuration) int {
+	var c int
+	switch o.SortBy {
+	case SortByName:
+		c = strings.Compare(a.Name, b.Name)
+	case SortByUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	return c
+}
+
+// after reports whether a configuration comes after the cursor in the list order
+func (o ListOptions) after(config conf.Configuration, cursor *listCursor) bool {
+	var c int
+	if o.SortBy == SortByName {
+		c = strings.Compare(config.Name, cursor.Value)
+	} else {
+		value := config.CreatedAt
+		if o.SortBy == SortByUpdatedAt {
+			value = config.UpdatedAt
+		}
+		c = value.Compare(cursor.cursorTime())
+	}
+	if c == 0 {
+		c = strings.Compare(config.ID, cursor.ID)
+	}
+	if o.Descending {
+		return c < 0
+	}
+	return c > 0
+}
+
+// pageOf cuts a page out of configurations that are already filtered,
+// sorted and past the cursor. It takes up to Limit+1 of them, the extra one
+// telling whether there is a next page.
+func (o ListOptions) pageOf(configs []conf.Configuration) ConfigPage {
+	page := ConfigPage{Configs: configs}
+	if o.Limit > 0 && len(configs) > o.Limit {
+		page.Configs = configs[:o.Limit]
+		page.NextCursor = o.encodeCursor(page.Configs[o.Limit-1])
+	}
+	if page.Configs == nil {
+		page.Configs = []conf.Configuration{}
+	}
+	return page
+}
+
+// listInMemory pages through configurations held in memory, for databases
+// without indexes to do it
+func listInMemory(configs []conf.Configuration, options ListOptions) (ConfigPage, error) {
+	options, err := options.normalize()
+	if err != nil {
+		return ConfigPage{}, err
+	}
+	cursor, err := options.decodeCursor()
+	if err != nil {
+		return ConfigPage{}, err
+	}
+
+	var selected []conf.Configuration
+	for _, config := range configs {
+		if options.matches(config) && (cursor == nil || options.after(config, cursor)) {
+			selected = append(selected, config)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if options.Descending {
+			return options.compare(selected[i], selected[j]) > 0
+		}
+		return options.compare(selected[i], selected[j]) < 0
+	})
+	if options.Limit > 0 && len(selected) > options.Limit+1 {
+		selected = selected[:options.Limit+1]
+	}
+	return options.pageOf(selected), nil
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// testListConfigs checks the ListConfigs contract on an empty database
+func testListConfigs(t *testing.T, db Database) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fixtures := []struct {
+		name     string
+		sinkType string
+		labels   map[string]string
+		disabled bool
+	}{
+		{"orders", "stdout", map[string]string{"team": "shop", "env": "prod"}, false},
+		{"orders-eu", "stdout", map[string]string{"team": "shop", "env": "dev"}, false},
+		{"billing", "jsonl", map[string]string{"team": "finance", "env": "prod"}, false},
+		{"orders-us", "jsonl", nil, true},
+		{"audit", "stdout", map[string]string{"env": "prod"}, true},
+	}
+	ids := map[string]string{}
+	for k, f := range fixtures {
+		config := conf.NewConfiguration(f.name, conf.Sink{Type: f.sinkType, Config: map[string]interface{}{}}, false)
+		config.Labels = f.labels
+		config.Disabled = f.disabled
+		config.CreatedAt = start.Add(time.Duration(k) * time.Minute)
+		config.UpdatedAt = start.Add(time.Duration(len(fixtures)-k) * time.Minute)
+		inserted, err := db.InsertConfig(ctx, config)
+		if err != nil {
+			t.Fatalf("Failed to insert config: %v", err)
+		}
+		ids[f.name] = inserted.ID
+	}
+
+	names := func(configs []conf.Configuration) []string {
+		result := []string{}
+		for _, config := range configs {
+			result = append(result, config.Name)
+		}
+		return result
+	}
+	// list follows the cursors through every page
+	list := func(options ListOptions) []string {
+		result := []string{}
+		for {
+			page, err := db.ListConfigs(ctx, options)
+			if err != nil {
+				t.Fatalf("Failed to list configurations with %+v: %v", options, err)
+			}
+			if options.Limit > 0 && len(page.Configs) > options.Limit {
+				t.Fatalf("Page of %d configurations over the limit of %d", len(page.Configs), options.Limit)
+			}
+			result = append(result, names(page.Configs)...)
+			if page.NextCursor == "" {
+				return result
+			}
+			options.Cursor = page.NextCursor
+		}
+	}
+	enabled, disabled := false, true
+
+	cases := []struct {
+		options ListOptions
+		want    []string
+	}{
+		{ListOptions{}, []string{"orders", "orders-eu", "billing", "orders-us", "audit"}},
+		{ListOptions{Descending: true, Limit: 2}, []string{"audit", "orders-us", "billing", "orders-eu", "orders"}},
+		{ListOptions{SortBy: SortByName, Limit: 2}, []string{"audit", "billing", "orders", "orders-eu", "orders-us"}},
+		{ListOptions{SortBy: SortByUpdatedAt, Limit: 1}, []string{"audit", "orders-us", "billing", "orders-eu", "orders"}},
+		{ListOptions{NamePrefix: "orders-", SortBy: SortByName, Descending: true}, []string{"orders-us", "orders-eu"}},
+		{ListOptions{SinkType: "jsonl"}, []string{"billing", "orders-us"}},
+		{ListOptions{Labels: map[string]string{"env": "prod"}, Limit: 1}, []string{"orders", "billing", "audit"}},
+		{ListOptions{Labels: map[string]string{"env": "prod", "team": "shop"}}, []string{"orders"}},
+		{ListOptions{Disabled: &enabled, NamePrefix: "orders"}, []string{"orders", "orders-eu"}},
+		{ListOptions{Disabled: &disabled, Limit: 1}, []string{"orders-us", "audit"}},
+		{ListOptions{NamePrefix: "missing"}, []string{}},
+	}
+	for _, c := range cases {
+		got := list(c.options)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Listing with %+v: expected %v, got %v", c.options, c.want, got)
+		}
+	}
+
+	// Deleting the last configuration of a page doesn't skip the next one
+	page, err := db.ListConfigs(ctx, ListOptions{SortBy: SortByName, Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to list configurations: %v", err)
+	}
+	if _, err := db.DeleteConfig(ctx, ids["billing"], AnyVersion); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	page, err = db.ListConfigs(ctx, ListOptions{SortBy: SortByName, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("Failed to list configurations: %v", err)
+	}
+	if got := names(page.Configs); !reflect.DeepEqual(got, []string{"orders", "orders-eu"}) {
+		t.Errorf("Expected the page after a deleted configuration to be [orders orders-eu], got %v", got)
+	}
+
+	// Cursors only work with the sort they were issued for
+	invalid := []ListOptions{
+		{SortBy: "sink_type"},
+		{Limit: -1},
+		{Cursor: "not a cursor"},
+		{SortBy: SortByName, Descending: true, Cursor: page.NextCursor},
+	}
+	for _, options := range invalid {
+		_, err := db.ListConfigs(ctx, options)
+		if !errors.Is(err, ErrInvalidListOptions) {
+			t.Errorf("Expected ErrInvalidListOptions listing with %+v, got %v", options, err)
+		}
+	}
+}
+
+func TestInMemoryListConfigs(t *testing.T) {
+	testListConfigs(t, NewInMemoryDB())
+}
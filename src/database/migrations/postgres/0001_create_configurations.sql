@@ -0,0 +1,12 @@
+CREATE TABLE configurations (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    use_key     BOOLEAN NOT NULL DEFAULT FALSE,
+    key_hash    TEXT NOT NULL DEFAULT '',
+    sink_type   TEXT NOT NULL,
+    sink_config JSONB NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX configurations_name_key ON configurations (name);
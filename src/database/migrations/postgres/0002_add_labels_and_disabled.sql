@@ -0,0 +1,10 @@
+ALTER TABLE configurations
+    ADD COLUMN labels   JSONB   NOT NULL DEFAULT '{}',
+    ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Keyset pagination orders by the sort field, then id
+CREATE INDEX configurations_created_at_idx ON configurations (created_at, id);
+CREATE INDEX configurations_updated_at_idx ON configurations (updated_at, id);
+CREATE INDEX configurations_name_c_idx ON configurations ((name COLLATE "C"), id);
+
+CREATE INDEX configurations_labels_idx ON configurations USING GIN (labels);
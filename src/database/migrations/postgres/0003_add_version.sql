@@ -0,0 +1,2 @@
+-- Rows written before versioning start at 0, new ones at 1
+ALTER TABLE configurations ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
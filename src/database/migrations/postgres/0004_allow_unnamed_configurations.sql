@@ -0,0 +1,3 @@
+-- Names are optional, only non-empty ones must be unique
+DROP INDEX configurations_name_key;
+CREATE UNIQUE INDEX configurations_name_key ON configurations (name) WHERE name <> '';
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// Configuration changes
+/*
+	Databases that can push configuration changes implement ConfigWatcher,
+	so every instance learns about updates and deletions made through the
+	others as they happen. Databases that can't are only picked up by
+	periodically listing the configurations.
+*/
+type ConfigChangeKind string
+
+const (
+	ConfigAdded    ConfigChangeKind = "added"
+	ConfigModified ConfigChangeKind = "modified"
+	ConfigRemoved  ConfigChangeKind = "removed"
+)
+
+type ConfigChange struct {
+	Kind   ConfigChangeKind
+	Config conf.Configuration // For removals, only the ID is guaranteed to be set
+}
+
+type ConfigWatcher interface {
+	// WatchConfigs calls handle for every change until ctx is done or the
+	// watch fails. The existing configurations are first reported as added.
+	WatchConfigs(ctx context.Context, handle func(ConfigChange)) error
+}
@@ -4,13 +4,19 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -23,17 +29,6 @@ import (
 	"github.com/altxtech/webhook-connector/src/utils"
 )
 
-// Initialization
-func initDB() (database.Database){
-	db, err := database.NewFirestoreDatabase(context.Background(), os.Getenv("DATABASE_ID"))
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
-	}
-	return db
-}
-var db database.Database = initDB()
-
-
 // API Interface
 type ConfigOperationRequest struct {
 	// Request for creating new configurations and updating new ones
@@ -43,6 +38,8 @@ type ConfigOperationRequest struct {
 		Type string `json:"type"`
 		Config map[string]interface{}
 	} `json:"sink"`
+	Labels map[string]string `json:"labels"`
+	Disabled bool `json:"disabled"`
 }
 
 type ConfigCreationRestul struct {
@@ -55,6 +52,8 @@ type ConfigCreationRestul struct {
 		Type string `json:"type"`
 		Config map[string]interface{}
 	} `json:"sink"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Disabled bool `json:"disabled"`
 }
 
 type APIErrorResponse struct {
@@ -65,13 +64,72 @@ func NewAPIErrorResponse(error string) APIErrorResponse {
 	return APIErrorResponse{Error: error}
 }
 
+// databaseErrorStatus picks the response status for a database error
+func databaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, database.ErrConflict), errors.Is(err, database.ErrVersionMismatch):
+		return http.StatusConflict
+	case errors.Is(err, database.ErrInvalidListOptions):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Optimistic concurrency
+/*
+	GET and PUT return the version of a configuration as its ETag. PUT and
+	DELETE with an If-Match header only apply to that version, and answer
+	412 Precondition Failed once another write changed it. Without If-Match
+	they apply to whatever version is stored, and only fail with 409 if it
+	changes while they run.
+*/
+func configETag(config conf.Configuration) string {
+	return fmt.Sprintf(`"%d"`, config.Version)
+}
+
+// ifMatch reports whether the If-Match header, if any, matches the current
+// version of a configuration. Weak ETags never match.
+func ifMatch(c *gin.Context, current conf.Configuration) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+	etag := configETag(current)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeErrorStatus picks the response status for a failed conditional write
+func writeErrorStatus(c *gin.Context, err error) int {
+	if errors.Is(err, database.ErrVersionMismatch) && c.GetHeader("If-Match") != "" {
+		return http.StatusPreconditionFailed
+	}
+	return databaseErrorStatus(err)
+}
+
+func respondPreconditionFailed(c *gin.Context, current conf.Configuration) {
+	message := fmt.Sprintf("Configuration with id %s was modified, its current ETag is %s.", current.ID, configETag(current))
+	c.Header("ETag", configETag(current))
+	c.IndentedJSON(http.StatusPreconditionFailed, NewAPIErrorResponse(message))
+}
+
 func helloWorld(c *gin.Context) {
 	c.String(http.StatusOK, "Hello webhook connector!")
 }
 
 // Handlers
 // Configurations
-func CreateConfig(c *gin.Context) {
+func (s *Server) CreateConfig(c *gin.Context) {
 
 	// Read and validate request
 	var request ConfigOperationRequest
@@ -93,11 +151,13 @@ func CreateConfig(c *gin.Context) {
 	}
 	
 	// Insert into database
-	idConfig, err := db.InsertConfig(newConfig)
+	ctx, cancel := s.dbContext(c)
+	defer cancel()
+	idConfig, err := s.db.InsertConfig(ctx, newConfig)
 	if err != nil {
 		message := fmt.Sprintf("Failed to insert configuration into database: %v", err)
 		response := NewAPIErrorResponse(message)
-		c.IndentedJSON(http.StatusBadRequest, response)
+		c.IndentedJSON(databaseErrorStatus(err), response)
 		return
 	}
 
@@ -106,6 +166,8 @@ func CreateConfig(c *gin.Context) {
 		Name: idConfig.Name,
 		UseKey: idConfig.UseKey,
 		Sink: struct{Type string "json:\"type\""; Config map[string]interface{}}(idConfig.Sink),
+		Labels: idConfig.Labels,
+		Disabled: idConfig.Disabled,
 	}
 
 	// Create a webhook key
@@ -119,7 +181,7 @@ func CreateConfig(c *gin.Context) {
 		}
 		hash := CreateKeyHashPair(idConfig.ID, key)
 		idConfig.SetKeyHash(hash)
-		_, err = db.UpdateConfig(idConfig)
+		_, err = s.db.UpdateConfig(ctx, idConfig)
 		if err != nil {
 			message := fmt.Sprintf("Failed to generate webhook key: %v", err)
 			response := NewAPIErrorResponse(message)
@@ -145,20 +207,93 @@ func ConfigFromRequest(request ConfigOperationRequest) (conf.Configuration, erro
 	}
 	newConfig = conf.NewConfiguration(request.Name, sinkConf, request.UseKey)
 
+	err = conf.ValidateLabels(request.Labels)
+	if err != nil {
+		return newConfig, fmt.Errorf("Invalid labels: %v", err)
+	}
+	newConfig.Labels = request.Labels
+	newConfig.Disabled = request.Disabled
+
 	return newConfig, nil
 }
 
 // List configs
-func ListConfigs(c *gin.Context) {
-	configs, err := db.ListConfigs()
+/*
+	Returns a page of configurations and the cursor of the next one, which
+	is passed back as ?cursor= along with the same sort. Query parameters:
+
+	- name_prefix, sink_type: filter on the name and sink type
+	- label=key:value: filter on a label, repeatable to require several
+	- disabled=true|false: filter on the disabled state
+	- sort: created_at (default), updated_at or name, prefixed with - for
+	  descending order
+	- limit: page size, up to maxPageSize
+*/
+const (
+	defaultPageSize = 100
+	maxPageSize = 1000
+)
+
+func (s *Server) ListConfigs(c *gin.Context) {
+	options, err := listOptionsFromQuery(c)
+	if err != nil {
+		c.IndentedJSON(http.StatusBadRequest, NewAPIErrorResponse(err.Error()))
+		return
+	}
+
+	ctx, cancel := s.dbContext(c)
+	defer cancel()
+	page, err := s.db.ListConfigs(ctx, options)
 	if err != nil {
 		message := fmt.Sprintf("Failed to retrieve configurations: %v", err)
 		response := NewAPIErrorResponse(message)
-		c.IndentedJSON(http.StatusInternalServerError, response)
+		c.IndentedJSON(databaseErrorStatus(err), response)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, configs)
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+func listOptionsFromQuery(c *gin.Context) (database.ListOptions, error) {
+	options := database.ListOptions{
+		NamePrefix: c.Query("name_prefix"),
+		SinkType: c.Query("sink_type"),
+		Cursor: c.Query("cursor"),
+		Limit: defaultPageSize,
+	}
+
+	for _, label := range c.QueryArray("label") {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || key == "" {
+			return options, fmt.Errorf("Invalid label filter %s, expected key:value", label)
+		}
+		if options.Labels == nil {
+			options.Labels = map[string]string{}
+		}
+		options.Labels[key] = value
+	}
+
+	if value, ok := c.GetQuery("disabled"); ok {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fmt.Errorf("Invalid disabled filter %s, expected true or false", value)
+		}
+		options.Disabled = &disabled
+	}
+
+	sort := c.Query("sort")
+	options.Descending = strings.HasPrefix(sort, "-")
+	options.SortBy = strings.TrimPrefix(sort, "-")
+
+	if value, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return options, fmt.Errorf("Invalid limit %s, expected 1 to %d", value, maxPageSize)
+		}
+		options.Limit = limit
+	}
+
+	return options, nil
 }
 
 
@@ -212,20 +347,24 @@ func CheckKey(salt string, key string, keyHash string) bool {
 }
 
 
-func GetConfig(c *gin.Context) {
+func (s *Server) GetConfig(c *gin.Context) {
 	id := c.Param("id")
-	config, err := db.GetConfigByID(id)
+	ctx, cancel := s.dbContext(c)
+	defer cancel()
+	// Read past the cache, so the ETag is the stored version
+	config, err := s.store.GetConfigByID(ctx, id)
 	if err != nil {
-		response := NewAPIErrorResponse(fmt.Sprintf("Configuration with id %s not found.", id))
-		c.IndentedJSON(http.StatusNotFound, response)
+		response := NewAPIErrorResponse(fmt.Sprintf("Failed to retrieve configuration: %v", err))
+		c.IndentedJSON(databaseErrorStatus(err), response)
 		return
 	}
 
+	c.Header("ETag", configETag(config))
 	c.IndentedJSON(http.StatusOK, config)
 	return
 }
 
-func UpdateConfig(c *gin.Context) {
+func (s *Server) UpdateConfig(c *gin.Context) {
 	var request ConfigOperationRequest
 	err := c.ShouldBindJSON(&request)
 	if err != nil {
@@ -235,11 +374,18 @@ func UpdateConfig(c *gin.Context) {
 
 	// Retrieve the existing configuration
 	id := c.Param("id")
-	oldConfig, err := db.GetConfigByID(id)
+	ctx, cancel := s.dbContext(c)
+	defer cancel()
+	// Read past the cache, so If-Match is checked against the stored version
+	oldConfig, err := s.store.GetConfigByID(ctx, id)
 	if err != nil {
 		message := fmt.Sprintf("Error retrieving existing configuration: %v", err)
 		response := NewAPIErrorResponse(message)
-		c.IndentedJSON(http.StatusBadRequest, response)
+		c.IndentedJSON(databaseErrorStatus(err), response)
+		return
+	}
+	if !ifMatch(c, oldConfig) {
+		respondPreconditionFailed(c, oldConfig)
 		return
 	}
 	
@@ -254,18 +400,23 @@ func UpdateConfig(c *gin.Context) {
 
 	// Set inherited fields from existing config
 	updatedConfig.SetID(id)
+	updatedConfig.Version = oldConfig.Version
 	updatedConfig.CreatedAt = oldConfig.CreatedAt
+	// The webhook key stays valid while the configuration keeps using it
+	if updatedConfig.UseKey && oldConfig.UseKey {
+		updatedConfig.SetKeyHash(oldConfig.KeyHash)
+	}
 
-	result, err := db.UpdateConfig(updatedConfig)
+	result, err := s.db.UpdateConfig(ctx, updatedConfig)
 	if err != nil {
 		message := fmt.Sprintf("Error Updating configurations: %v", err)
 		response := NewAPIErrorResponse(message)
-		c.IndentedJSON(http.StatusBadRequest, response)
+		c.IndentedJSON(writeErrorStatus(c, err), response)
 		return
 	}
 
 	// If there is an active sink for this configuration, end it
-	_, err = sm.terminateSinkIfExists(id)
+	err = s.sinks.Remove(c.Request.Context(), id)
 	if err != nil {
 		message := fmt.Sprintf("Error deleting existing sink for configuration: %v", err)
 		response := NewAPIErrorResponse(message)
@@ -273,17 +424,46 @@ func UpdateConfig(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", configETag(result))
 	c.IndentedJSON(http.StatusOK, result)
 	return
 }
 
-func DeleteConfig(c *gin.Context){
+func (s *Server) DeleteConfig(c *gin.Context){
 	id := c.Param("id")
-	deletedConfig, err := db.DeleteConfig(id)
+	ctx, cancel := s.dbContext(c)
+	defer cancel()
+
+	version := database.AnyVersion
+	if c.GetHeader("If-Match") != "" {
+		current, err := s.store.GetConfigByID(ctx, id)
+		if err != nil {
+			message := fmt.Sprintf("Failed to delete config with id %s: %v", id, err)
+			response := NewAPIErrorResponse(message)
+			c.IndentedJSON(databaseErrorStatus(err), response)
+			return
+		}
+		if !ifMatch(c, current) {
+			respondPreconditionFailed(c, current)
+			return
+		}
+		version = current.Version
+	}
+
+	deletedConfig, err := s.db.DeleteConfig(ctx, id, version)
 	if err != nil {
 		message := fmt.Sprintf("Failed to delete config with id %s: %v", id, err)
 		response := NewAPIErrorResponse(message)
-		c.IndentedJSON(http.StatusNotFound, response)
+		c.IndentedJSON(writeErrorStatus(c, err), response)
+		return
+	}
+
+	// Flush and close the sink of the deleted configuration
+	err = s.sinks.Remove(c.Request.Context(), id)
+	if err != nil {
+		message := fmt.Sprintf("Error closing sink for deleted configuration: %v", err)
+		response := NewAPIErrorResponse(message)
+		c.IndentedJSON(http.StatusInternalServerError, response)
 		return
 	}
 
@@ -292,8 +472,210 @@ func DeleteConfig(c *gin.Context){
 }
 
 
+// Sink connectivity checks
+/*
+	Builds a throwaway sink for a configuration, without touching the one
+	serving its webhooks, and either writes a synthetic event through it
+	(mode=write, the default) or only checks the destination is reachable
+	with the sink's Health check (mode=check).
+
+	Checks make the service connect to any destination the caller names, so
+	they require the SINK_CHECK_TOKEN bearer token and are disabled without
+	one. The result only reports the stage that failed: the sink's own error
+	can carry the destination's response, so it is logged instead.
+*/
+const sinkCheckTimeout = 30 * time.Second
+
+type SinkCheckResult struct {
+	Success bool `json:"success"`
+	Mode string `json:"mode"`
+	Stage string `json:"stage,omitempty"` // Stage that failed: create, write, flush, health or close
+	LatencyMs int64 `json:"latency_ms"`
+	Error string `json:"error,omitempty"`
+}
+
+func checkSink(ctx context.Context, newSink func(conf.Sink) (sink.Sink, error), sinkConf conf.Sink, mode string, event *model.WebhookEvent) SinkCheckResult {
+
+	result := SinkCheckResult{Mode: mode}
+	start := time.Now()
+	fail := func(stage string, err error) SinkCheckResult {
+		log.Printf("Sink check for source %s failed at %s: %v", event.Metadata.SourceId, stage, err)
+		result.Stage = stage
+		result.Error = fmt.Sprintf("The sink failed at the %s stage. Details are in the service logs.", stage)
+		result.LatencyMs = time.Since(start).Milliseconds()
+		return result
+	}
+
+	checkedSink, err := newSink(sinkConf)
+	if err != nil {
+		return fail("create", err)
+	}
+
+	stage := "health"
+	if mode == "check" {
+		err = checkedSink.Health(ctx)
+	} else {
+		stage = "write"
+		event.Metadata.LoadedAt = timestamppb.Now()
+		err = checkedSink.WriteRows(ctx, []protoreflect.ProtoMessage{event})
+		if err == nil {
+			stage = "flush"
+			err = checkedSink.Flush(ctx)
+		}
+	}
+
+	// Sinks are closed exactly once, whatever the outcome
+	closeErr := checkedSink.Close()
+	if err != nil {
+		return fail(stage, err)
+	}
+	if closeErr != nil {
+		return fail("close", closeErr)
+	}
+
+	result.Success = true
+	result.LatencyMs = time.Since(start).Milliseconds()
+	return result
+}
+
+func syntheticEvent(sourceID string, sourceName string) *model.WebhookEvent {
+	return &model.WebhookEvent{
+		Metadata: &model.Metadata{
+			ReceivedAt: timestamppb.Now(),
+			SourceId: sourceID,
+			SourceName: sourceName,
+		},
+		Event: `{"webhook_connector_test": true}`,
+	}
+}
+
+// requireSinkCheckToken aborts requests without the SINK_CHECK_TOKEN bearer token
+func (s *Server) requireSinkCheckToken(c *gin.Context) {
+	if s.config.SinkCheckToken == "" {
+		response := NewAPIErrorResponse("Sink checks are disabled. Set SINK_CHECK_TOKEN to enable them.")
+		c.AbortWithStatusJSON(http.StatusForbidden, response)
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.SinkCheckToken)) != 1 {
+		response := NewAPIErrorResponse("Unauthorized. Invalid sink check token.")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+		return
+	}
+	c.Next()
+}
+
+func sinkCheckMode(c *gin.Context) (string, bool) {
+	mode := c.DefaultQuery("mode", "write")
+	if mode != "write" && mode != "check" {
+		response := NewAPIErrorResponse("Parameter mode must be one of write, check.")
+		c.IndentedJSON(http.StatusBadRequest, response)
+		return mode, false
+	}
+	return mode, true
+}
+
+func respondSinkCheck(c *gin.Context, result SinkCheckResult) {
+	if !result.Success {
+		c.IndentedJSON(http.StatusBadGateway, result)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, result)
+}
+
+// Check the sink of a saved configuration
+func (s *Server) CheckConfigSink(c *gin.Context) {
+	mode, ok := sinkCheckMode(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	dbCtx, dbCancel := s.dbContext(c)
+	defer dbCancel()
+	config, err := s.db.GetConfigByID(dbCtx, id)
+	if err != nil {
+		response := NewAPIErrorResponse(fmt.Sprintf("Failed to retrieve configuration: %v", err))
+		c.IndentedJSON(databaseErrorStatus(err), response)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), sinkCheckTimeout)
+	defer cancel()
+	respondSinkCheck(c, checkSink(ctx, s.newSink, config.Sink, mode, syntheticEvent(config.ID, config.Name)))
+}
+
+// Check an unsaved sink definition (dry run)
+func (s *Server) CheckSinkDefinition(c *gin.Context) {
+	mode, ok := sinkCheckMode(c)
+	if !ok {
+		return
+	}
+
+	var request ConfigOperationRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		message := fmt.Sprintf("Invalid configuration object: %v", err)
+		response := NewAPIErrorResponse(message)
+		c.IndentedJSON(http.StatusBadRequest, response)
+		return
+	}
+	sinkConf, err := conf.NewSink(request.Sink.Type, request.Sink.Config)
+	if err != nil {
+		message := fmt.Sprintf("Failed to process sink configuration: %v", err)
+		response := NewAPIErrorResponse(message)
+		c.IndentedJSON(http.StatusBadRequest, response)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), sinkCheckTimeout)
+	defer cancel()
+	respondSinkCheck(c, checkSink(ctx, s.newSink, sinkConf, mode, syntheticEvent("dry-run", request.Name)))
+}
+
+
+// Sink types
+func ListSinkTypes(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, conf.SinkSchemas())
+}
+
+
+// Readiness
+/*
+	Checks the instance can read configurations, so it is taken out of
+	rotation when it loses its database.
+
+	Sink destinations are supplied by users, so their health doesn't gate
+	readiness: one tenant's broken bucket or broker would take every
+	instance out. It is reported by SinkHealth instead.
+*/
+const readinessTimeout = 10 * time.Second
+
+func (s *Server) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	_, err := s.store.ListConfigs(ctx, database.ListOptions{Limit: 1})
+	if err != nil {
+		c.IndentedJSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": err.Error()})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// SinkHealth checks the destinations of the live sinks. It always answers
+// 200, the failures are informational.
+func (s *Server) SinkHealth(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	failures := s.sinks.Health(ctx)
+	c.IndentedJSON(http.StatusOK, gin.H{"unhealthy": len(failures), "sinks": failures})
+}
+
+
 // Ingesting webhooks
-func IngestWebhook(c *gin.Context){
+func (s *Server) IngestWebhook(c *gin.Context){
 
 	event := model.WebhookEvent{
 		Metadata: &model.Metadata{
@@ -303,11 +685,16 @@ func IngestWebhook(c *gin.Context){
 	}
 
 	// Validate id exists
-	config, err := db.GetConfigByID(c.Param("id"))
+	dbCtx, dbCancel := s.dbContext(c)
+	defer dbCancel()
+	config, err := s.db.GetConfigByID(dbCtx, c.Param("id"))
 	if err != nil {
 		message := fmt.Sprintf("Config with id %s not found.", c.Param("id"))
+		if !errors.Is(err, database.ErrNotFound) {
+			message = fmt.Sprintf("Failed to retrieve config with id %s: %v", c.Param("id"), err)
+		}
 		response := NewAPIErrorResponse(message)
-		c.IndentedJSON(http.StatusNotFound, response)
+		c.IndentedJSON(databaseErrorStatus(err), response)
 		return
 	}
 	event.Metadata.SourceId = config.ID
@@ -325,6 +712,12 @@ func IngestWebhook(c *gin.Context){
 			return
 		}
 	}
+
+	if config.Disabled {
+		response := NewAPIErrorResponse(fmt.Sprintf("Config with id %s is disabled.", config.ID))
+		c.IndentedJSON(http.StatusForbidden, response)
+		return
+	}
 	
 	// Read body data
 	data, err := io.ReadAll(c.Request.Body) 
@@ -346,12 +739,13 @@ func IngestWebhook(c *gin.Context){
 	event.Event = string(data)
 
 	// Get sink for configuration
-	thisSink, err := sm.getSink(&config)	
+	thisSink, release, err := s.sinks.Acquire(&config)
 	if err != nil {
 		response := NewAPIErrorResponse(fmt.Sprintf("Failed to get sink for config %s: %v", config.ID, err))
 		c.IndentedJSON(http.StatusBadRequest, response)
 		return
 	}
+	defer release()
 
 	// Write rows
 	/*
@@ -359,7 +753,8 @@ func IngestWebhook(c *gin.Context){
 		We COULD write to a buffer and have the output to the sink be done in batches.
 		There are pros and cons of doing it like this. Consider.
 	*/
-	err = thisSink.WriteRows([]protoreflect.ProtoMessage{&event})
+	ctx := sink.WithRequestHeaders(c.Request.Context(), c.Request.Header)
+	err = thisSink.WriteRows(ctx, []protoreflect.ProtoMessage{&event})
 	if err != nil {
 		response := NewAPIErrorResponse(fmt.Sprintf("Failed to write rows to sink: %v", err))
 		c.IndentedJSON(http.StatusBadRequest, response)
@@ -371,67 +766,26 @@ func IngestWebhook(c *gin.Context){
 }
 
 
-
-
-
-
-// Type to manage sinks
-type SinkManager map[string]sink.Sink
-func NewSinkManager() SinkManager {
-	return SinkManager{}
-}
-func (sm SinkManager) getSink(config *conf.Configuration) (sink.Sink, error){
-	// If the sink for this configuration exists, return it.
-	// If not, build it
-
-	var result sink.Sink
-	result, ok := sm[config.ID]
-	if ok {
-		return result, nil
-	}
-
-	// Create the appriate sink based on sink
-	result, err := sink.NewSink(config.Sink)
+func main() {
+	config, err := LoadConfig(os.Args[1:])
 	if err != nil {
-		return result, fmt.Errorf("Failed to create new sink: %v", err)
+		log.Fatalf("Invalid configuration: %v", err)
 	}
+	// Request logs share stdout with the stdout sinks
+	gin.DefaultWriter = sink.Stdout
 
-	// Register new sink
-	sm[config.ID] = result
-	return result, nil
-}
-func (sm SinkManager) terminateSinkIfExists(id string) (sink.Sink, error){
-
-	/*
-		Terminate a sink if it exists.
-		Returns a copy of the terminated sink.
-	*/
+	// Background work stops with the first signal
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	var termSink sink.Sink
-	termSink, ok := sm[id]
-	if ok {
-		err := termSink.Close()
-		if err != nil {
-			return termSink, fmt.Errorf("Error terminating sink: %v", err)
-		}
-		delete(sm, id)
+	server, err := NewServer(ctx, config)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
 	}
-	return termSink, nil
-}
-var sm SinkManager = NewSinkManager()
+	log.Printf("Using %s database", config.DatabaseBackend)
 
-func main() {
-	router := gin.Default()
-	router.GET("/hello-world", helloWorld)
-
-	// Configurations
-	router.POST("/configurations", CreateConfig)
-	router.GET("/configurations", ListConfigs)
-	router.GET("/configurations/:id", GetConfig)
-	router.PUT("/configurations/:id", UpdateConfig)
-	router.DELETE("/configurations/:id", DeleteConfig)
-
-	router.POST("/ingest/:id", IngestWebhook)
-
-	router.Run()
+	err = server.Run(ctx)
+	if err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
 }
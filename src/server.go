@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/database"
+	"github.com/altxtech/webhook-connector/src/sink"
+)
+
+// Server
+/*
+	Holds everything the handlers share: the configuration database, read
+	through a cache, and the live sinks.
+*/
+type Server struct {
+	config      Config
+	store       database.Database // The database itself
+	configCache *database.ConfigCache
+	db          database.Database // The database read through the cache
+	sinks       *sink.SinkManager
+	newSink     func(conf.Sink) (sink.Sink, error) // Builds the throwaway sinks of checks
+}
+
+func NewServer(ctx context.Context, config Config) (*Server, error) {
+	store, err := database.Open(ctx, config.DatabaseBackend, config.DatabaseSource)
+	if err != nil {
+		return nil, err
+	}
+	return NewServerWithDatabase(config, store), nil
+}
+
+func NewServerWithDatabase(config Config, store database.Database) *Server {
+	configCache := database.NewConfigCache(store, database.ConfigCacheOptions{
+		TTL:         config.ConfigCacheTTL,
+		NegativeTTL: config.ConfigCacheNegativeTTL,
+		MaxEntries:  config.ConfigCacheMaxEntries,
+	})
+	return &Server{
+		config:      config,
+		store:       store,
+		configCache: configCache,
+		db:          configCache,
+		sinks: sink.NewSinkManager(sink.SinkManagerOptions{
+			Capacity:    config.SinkCapacity,
+			IdleTimeout: config.SinkIdleTimeout,
+		}),
+		newSink: sink.NewSink,
+	}
+}
+
+func (s *Server) Router() *gin.Engine {
+	router := gin.Default()
+	router.GET("/hello-world", helloWorld)
+
+	// Configurations
+	router.POST("/configurations", s.CreateConfig)
+	router.GET("/configurations", s.ListConfigs)
+	router.GET("/configurations/:id", s.GetConfig)
+	router.PUT("/configurations/:id", s.UpdateConfig)
+	router.DELETE("/configurations/:id", s.DeleteConfig)
+	router.POST("/configurations/test", s.requireSinkCheckToken, s.CheckSinkDefinition)
+	router.POST("/configurations/:id/test", s.requireSinkCheckToken, s.CheckConfigSink)
+
+	router.GET("/sink-types", ListSinkTypes)
+
+	router.POST("/ingest/:id", s.IngestWebhook)
+
+	router.GET("/sinks", s.SinkStats)
+	router.GET("/sinks/health", s.SinkHealth)
+	router.GET("/config-cache", s.ConfigCacheStats)
+	router.GET("/readyz", s.Readiness)
+
+	return router
+}
+
+// Run serves until ctx is done, then shuts down gracefully
+func (s *Server) Run(ctx context.Context) error {
+	if s.config.SinkReconcileInterval > 0 {
+		go s.reconcileSinks(ctx, s.config.SinkReconcileInterval)
+	}
+	if watcher, ok := s.store.(database.ConfigWatcher); ok {
+		go s.watchConfigs(ctx, watcher)
+	}
+
+	server := &http.Server{
+		Addr:    ":" + s.config.Port,
+		Handler: s.Router(),
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("Shutting down")
+	s.shutdown(server, s.config.ShutdownTimeout)
+	return nil
+}
+
+// dbContext bounds a request's database calls by DATABASE_TIMEOUT. They are
+// also cancelled when the client goes away.
+func (s *Server) dbContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request.Context(), s.config.DatabaseTimeout)
+}
+
+// reconcileSinks periodically drops the sinks of configurations that were
+// deleted or updated through other instances. It covers databases that
+// can't push changes, and changes missed while a watch was down.
+func (s *Server) reconcileSinks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		listCtx, cancel := context.WithTimeout(ctx, s.config.DatabaseTimeout)
+		page, err := s.db.ListConfigs(listCtx, database.ListOptions{})
+		cancel()
+		if err != nil {
+			log.Printf("Failed to list configurations for sink reconciliation: %v", err)
+			continue
+		}
+		err = s.sinks.Reconcile(ctx, page.Configs)
+		if err != nil {
+			log.Printf("Failed to reconcile sinks: %v", err)
+		}
+	}
+}
+
+// watchConfigs follows configuration changes pushed by the database, so
+// sinks are rebuilt or closed as soon as another instance changes them
+func (s *Server) watchConfigs(ctx context.Context, watcher database.ConfigWatcher) {
+	for {
+		err := watcher.WatchConfigs(ctx, func(change database.ConfigChange) {
+			s.configCache.Apply(change)
+
+			var err error
+			switch change.Kind {
+			case database.ConfigModified:
+				err = s.sinks.Refresh(ctx, change.Config)
+			case database.ConfigRemoved:
+				err = s.sinks.Remove(ctx, change.Config.ID)
+			}
+			if err != nil {
+				log.Printf("Failed to apply %s change of configuration %s: %v", change.Kind, change.Config.ID, err)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Configuration watch stopped, restarting: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// Graceful shutdown
+/*
+	On SIGTERM (Cloud Run) or SIGINT the server stops accepting connections
+	and waits for in-flight requests, then every live sink is flushed and
+	closed, and the database client last. All of it must fit in
+	SHUTDOWN_TIMEOUT; Cloud Run kills the instance 10s after SIGTERM.
+*/
+func (s *Server) shutdown(server *http.Server, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		log.Printf("Failed to finish in-flight requests: %v", err)
+	}
+
+	// Sink and client Close calls can't be interrupted, so stop waiting on them at the deadline
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := s.sinks.Shutdown(ctx)
+		if err != nil {
+			log.Printf("Failed to flush sinks: %v", err)
+		}
+		if closer, ok := s.store.(io.Closer); ok {
+			err = closer.Close()
+			if err != nil {
+				log.Printf("Failed to close database: %v", err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+		log.Printf("Shutdown complete")
+	case <-ctx.Done():
+		log.Printf("Shutdown deadline exceeded, exiting with sinks still closing")
+	}
+}
+
+func (s *Server) SinkStats(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, s.sinks.Stats())
+}
+
+func (s *Server) ConfigCacheStats(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, s.configCache.Stats())
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/database"
+	"github.com/altxtech/webhook-connector/src/sink"
+)
+
+// newTestServer serves from store with the default settings. newSink
+// replaces the sink types when set.
+func newTestServer(t *testing.T, store database.Database, newSink func(conf.Sink) (sink.Sink, error)) *Server {
+	gin.SetMode(gin.TestMode)
+	values := map[string]string{}
+	for _, s := range settings {
+		values[s.key] = s.fallback
+	}
+	config, err := parseConfig(values)
+	if err != nil {
+		t.Fatalf("Failed to parse default settings: %v", err)
+	}
+
+	server := NewServerWithDatabase(config, store)
+	if newSink != nil {
+		server.sinks = sink.NewSinkManager(sink.SinkManagerOptions{NewSink: newSink})
+		server.newSink = newSink
+	}
+	t.Cleanup(func() { server.sinks.Close() })
+	return server
+}
+
+func serve(server *Server, method string, path string, body string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	server.Router().ServeHTTP(recorder, request)
+	return recorder
+}
+
+// brokenSink accepts rows but reports its destination as unreachable
+type brokenSink struct{}
+
+func (s brokenSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	return nil
+}
+func (s brokenSink) Flush(ctx context.Context) error  { return nil }
+func (s brokenSink) Health(ctx context.Context) error { return errors.New("bucket not found") }
+func (s brokenSink) Close() error                     { return nil }
+
+// unreachableDatabase fails every listing
+type unreachableDatabase struct {
+	database.Database
+}
+
+func (db unreachableDatabase) ListConfigs(ctx context.Context, options database.ListOptions) (database.ConfigPage, error) {
+	return database.ConfigPage{}, errors.New("connection refused")
+}
+
+func TestReadinessIgnoresSinkHealth(t *testing.T) {
+	server := newTestServer(t, database.NewInMemoryDB(), func(conf.Sink) (sink.Sink, error) {
+		return brokenSink{}, nil
+	})
+	_, release, err := server.sinks.Acquire(&conf.Configuration{ID: "tenant", Sink: conf.Sink{Type: "stdout"}})
+	if err != nil {
+		t.Fatalf("Failed to acquire sink: %v", err)
+	}
+	release()
+
+	if response := serve(server, http.MethodGet, "/readyz", "", nil); response.Code != http.StatusOK {
+		t.Fatalf("Expected a broken sink to leave the instance ready, got %d: %s", response.Code, response.Body)
+	}
+	response := serve(server, http.MethodGet, "/sinks/health", "", nil)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "bucket not found") {
+		t.Fatalf("Expected the sink failure under /sinks/health, got %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestReadinessChecksDatabase(t *testing.T) {
+	server := newTestServer(t, unreachableDatabase{database.NewInMemoryDB()}, nil)
+	if response := serve(server, http.MethodGet, "/readyz", "", nil); response.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 without a database, got %d: %s", response.Code, response.Body)
+	}
+}
+
+// leakySink fails writes with the destination's response body
+type leakySink struct {
+	brokenSink
+}
+
+func (s leakySink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	return errors.New("internal metadata: secret-token")
+}
+
+func TestCheckSinkDefinition(t *testing.T) {
+	server := newTestServer(t, database.NewInMemoryDB(), func(sinkConf conf.Sink) (sink.Sink, error) {
+		if sinkConf.StringParam("format", "") == "cloud_logging" {
+			return leakySink{}, nil
+		}
+		return brokenSink{}, nil
+	})
+	body := `{"name": "dry", "sink": {"type": "stdout", "config": {"format": "json"}}}`
+	authorized := http.Header{"Authorization": {"Bearer check-token"}}
+
+	// Disabled without a token
+	if response := serve(server, http.MethodPost, "/configurations/test", body, authorized); response.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without SINK_CHECK_TOKEN, got %d: %s", response.Code, response.Body)
+	}
+
+	server.config.SinkCheckToken = "check-token"
+	wrong := http.Header{"Authorization": {"Bearer guess"}}
+	if response := serve(server, http.MethodPost, "/configurations/test", body, wrong); response.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 with a wrong token, got %d: %s", response.Code, response.Body)
+	}
+	if response := serve(server, http.MethodPost, "/configurations/test", body, authorized); response.Code != http.StatusOK {
+		t.Fatalf("Expected a successful check, got %d: %s", response.Code, response.Body)
+	}
+
+	// Failures name the stage, not the destination's response
+	leaky := `{"name": "dry", "sink": {"type": "stdout", "config": {"format": "cloud_logging"}}}`
+	response := serve(server, http.MethodPost, "/configurations/test", leaky, authorized)
+	if response.Code != http.StatusBadGateway || !strings.Contains(response.Body.String(), `"stage": "write"`) {
+		t.Fatalf("Expected a failed write stage, got %d: %s", response.Code, response.Body)
+	}
+	if strings.Contains(response.Body.String(), "secret-token") {
+		t.Fatalf("Expected the sink error to stay out of the response, got %s", response.Body)
+	}
+}
+
+func TestCheckConfigSink(t *testing.T) {
+	server := newTestServer(t, database.NewInMemoryDB(), func(conf.Sink) (sink.Sink, error) {
+		return brokenSink{}, nil
+	})
+	server.config.SinkCheckToken = "check-token"
+	authorized := http.Header{"Authorization": {"Bearer check-token"}}
+
+	config, err := server.db.InsertConfig(context.Background(), conf.NewConfiguration("saved", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	response := serve(server, http.MethodPost, "/configurations/"+config.ID+"/test?mode=check", "", authorized)
+	if response.Code != http.StatusBadGateway || !strings.Contains(response.Body.String(), `"stage": "health"`) {
+		t.Fatalf("Expected the health stage to fail, got %d: %s", response.Code, response.Body)
+	}
+	if response := serve(server, http.MethodPost, "/configurations/unknown/test", "", authorized); response.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for an unknown configuration, got %d: %s", response.Code, response.Body)
+	}
+	if response := serve(server, http.MethodPost, "/configurations/"+config.ID+"/test", "", nil); response.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a token, got %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestGetConfigETagIsCurrent(t *testing.T) {
+	server := newTestServer(t, database.NewInMemoryDB(), nil)
+	ctx := context.Background()
+	config, err := server.db.InsertConfig(ctx, conf.NewConfiguration("cached", conf.Sink{Type: "stdout"}, false))
+	if err != nil {
+		t.Fatalf("Failed to insert config: %v", err)
+	}
+	// Warm the cache, then change the configuration behind it, as another instance would
+	if _, err := server.db.GetConfigByID(ctx, config.ID); err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	updated, err := server.store.UpdateConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	response := serve(server, http.MethodGet, "/configurations/"+config.ID, "", nil)
+	if etag := response.Header().Get("ETag"); etag != configETag(updated) {
+		t.Fatalf("Expected ETag %s, got %s", configETag(updated), etag)
+	}
+}
+
+func TestCreateUnnamedConfigs(t *testing.T) {
+	server := newTestServer(t, database.NewInMemoryDB(), nil)
+	body := `{"sink": {"type": "stdout", "config": {}}}`
+	for i := 0; i < 2; i++ {
+		if response := serve(server, http.MethodPost, "/configurations", body, nil); response.Code != http.StatusOK {
+			t.Fatalf("Expected unnamed configuration %d to be created, got %d: %s", i+1, response.Code, response.Body)
+		}
+	}
+	named := `{"name": "orders", "sink": {"type": "stdout", "config": {}}}`
+	serve(server, http.MethodPost, "/configurations", named, nil)
+	if response := serve(server, http.MethodPost, "/configurations", named, nil); response.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for a duplicate name, got %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestUpdateKeepsWebhookKey(t *testing.T) {
+	server := newTestServer(t, database.NewInMemoryDB(), nil)
+	response := serve(server, http.MethodPost, "/configurations", `{"name": "keyed", "use_key": true, "sink": {"type": "stdout", "config": {}}}`, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Failed to create config: %d %s", response.Code, response.Body)
+	}
+	var created ConfigCreationRestul
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	update := func(useKey bool) {
+		body := fmt.Sprintf(`{"name": "renamed", "use_key": %t, "sink": {"type": "stdout", "config": {}}}`, useKey)
+		if response := serve(server, http.MethodPut, "/configurations/"+created.ID, body, nil); response.Code != http.StatusOK {
+			t.Fatalf("Failed to update config: %d %s", response.Code, response.Body)
+		}
+	}
+	ingest := func(key string) int {
+		header := http.Header{"Authorization": {"Bearer " + key}}
+		return serve(server, http.MethodPost, "/ingest/"+created.ID, `{"order": 1}`, header).Code
+	}
+
+	// The key issued on creation still works after an update
+	update(true)
+	if code := ingest(created.Key); code != http.StatusOK {
+		t.Fatalf("Expected the original key to be accepted, got %d", code)
+	}
+	if code := ingest("wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected a wrong key to be rejected, got %d", code)
+	}
+
+	// Turning the key off drops it
+	update(false)
+	stored, err := server.store.GetConfigByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	if stored.KeyHash != "" {
+		t.Fatal("Expected the key hash to be cleared")
+	}
+}
@@ -0,0 +1,202 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "amqp",
+		Description: "Publishes every event to an AMQP 0-9-1 (RabbitMQ) exchange with publisher confirms.",
+		Params: []conf.Param{
+			{Name: "url", Type: conf.ParamString, Required: true},
+			{Name: "exchange", Type: conf.ParamString, Default: "", Description: "Defaults to the default exchange."},
+			{Name: "routing_key", Type: conf.ParamString, Required: true, Description: "Template."},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewAMQPSink(
+			config.Config["url"].(string),
+			config.StringParam("exchange", ""),
+			config.Config["routing_key"].(string),
+		)
+	})
+}
+
+// AMQP 0-9-1 (RabbitMQ) Sink
+/*
+	Publishes every event as a persistent JSON message to an exchange and
+	waits for the broker's publisher confirm. The routing key is a template
+	(see template.go), e.g. "webhooks.{source_id}".
+
+	Messages are published as mandatory, so a message that no queue is
+	bound to receive is returned by the broker and fails the write instead
+	of being acked and dropped.
+
+	The connection is re-established on the next write after it drops.
+*/
+type amqpSink struct {
+	URL        string
+	Exchange   string
+	RoutingKey string
+	mu         sync.Mutex
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	returns    chan amqp.Return
+}
+
+func NewAMQPSink(url string, exchange string, routingKey string) (Sink, error) {
+	sink := &amqpSink{
+		URL:        url,
+		Exchange:   exchange,
+		RoutingKey: routingKey,
+	}
+	err := sink.connect()
+	if err != nil {
+		return sink, err
+	}
+	return sink, nil
+}
+
+func (sink *amqpSink) connect() error {
+	conn, err := amqp.Dial(sink.URL)
+	if err != nil {
+		return fmt.Errorf("Error connecting to AMQP broker: %v", err)
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Error opening channel: %v", err)
+	}
+	err = channel.Confirm(false)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Error enabling publisher confirms: %v", err)
+	}
+	// Unbuffered, so the broker's return is read before its confirm
+	sink.returns = channel.NotifyReturn(make(chan amqp.Return))
+	sink.conn = conn
+	sink.channel = channel
+	return nil
+}
+
+// reconnect reopens the connection when the broker or network dropped it
+func (sink *amqpSink) reconnect() error {
+	if sink.conn != nil && !sink.conn.IsClosed() && !sink.channel.IsClosed() {
+		return nil
+	}
+	if sink.conn != nil {
+		sink.disconnect()
+	}
+	return sink.connect()
+}
+
+// disconnect closes the connection while draining returns, since a pending
+// return would keep the connection from reading the broker's close-ok
+func (sink *amqpSink) disconnect() error {
+	go func(returns chan amqp.Return) {
+		for range returns {
+		}
+	}(sink.returns)
+	return sink.conn.Close()
+}
+
+func (sink *amqpSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	err := sink.reconnect()
+	if err != nil {
+		return err
+	}
+
+	// Publish everything first, then wait for the confirms
+	confirms := make([]*amqp.DeferredConfirmation, len(rows))
+	published := map[string]bool{}
+	for k, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		id, err := eventID(row)
+		if err != nil {
+			return err
+		}
+		published[id] = true
+		headers := amqp.Table{}
+		for key, value := range rowAttributes(row) {
+			headers[key] = value
+		}
+
+		confirms[k], err = sink.channel.PublishWithDeferredConfirmWithContext(
+			ctx,
+			sink.Exchange,
+			renderTemplate(sink.RoutingKey, rowPlaceholders(row)),
+			true,
+			false,
+			amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: amqp.Persistent,
+				MessageId:    id,
+				Headers:      headers,
+				Body:         encoded,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("Error publishing message: %v", err)
+		}
+	}
+
+	// The broker returns an unroutable message before confirming it, so keep
+	// reading returns until every confirm is in
+	var returned error
+	for _, confirm := range confirms {
+		for waiting := true; waiting; {
+			select {
+			case message := <-sink.returns:
+				// Returns of messages from an earlier, abandoned write are ignored
+				if returned == nil && published[message.MessageId] {
+					returned = fmt.Errorf("Message %s was returned by the broker: %d %s", message.MessageId, message.ReplyCode, message.ReplyText)
+				}
+			case <-confirm.Done():
+				waiting = false
+			case <-ctx.Done():
+				return fmt.Errorf("Error waiting for publisher confirm: %v", ctx.Err())
+			}
+		}
+		if !confirm.Acked() {
+			return fmt.Errorf("Message was nacked by the broker")
+		}
+	}
+	return returned
+}
+
+func (sink *amqpSink) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (sink *amqpSink) Health(ctx context.Context) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return sink.reconnect()
+}
+
+func (sink *amqpSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if sink.conn == nil || sink.conn.IsClosed() {
+		return nil
+	}
+	err := sink.disconnect()
+	if err != nil {
+		return fmt.Errorf("Error closing connection: %v", err)
+	}
+	return nil
+}
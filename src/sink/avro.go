@@ -0,0 +1,174 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/hamba/avro/v2/ocf"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "avro",
+		Description: "Writes rolling Avro container files of typed columns into a local directory, optionally uploading them to GCS or S3.",
+		Params: append(append(fileSinkParams(300),
+			conf.Param{Name: "compression", Type: conf.ParamString, Default: "snappy", Options: []string{"none", "snappy", "deflate"}},
+			conf.Param{Name: "block_rows", Type: conf.ParamInteger, Default: 1000},
+		), uploadParams()...),
+		Validate: func(s conf.Sink) error {
+			for _, column := range s.ColumnsParam("columns", nil) {
+				if !avroName.MatchString(column.Name) {
+					return fmt.Errorf("Column name '%s' is not a valid Avro name.", column.Name)
+				}
+			}
+			return validateUploadTo(s)
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		options := fileSinkOptions(config)
+		options.BlockRows = config.IntParam("block_rows", 0)
+		return NewAvroSink(options)
+	})
+}
+
+var avroName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Avro file Sink
+/*
+	Writes rolling Avro object container files of typed columns (see
+	columns.go) into a directory. Every field is a union with null, and
+	timestamps use the timestamp-micros logical type, which BigQuery reads as
+	TIMESTAMP when loading with logical types enabled.
+
+	With upload_to set, completed files are uploaded to GCS or S3 under the
+	given prefix and then removed from the directory (see rolling.go).
+*/
+type avroSink struct {
+	Options FileSinkOptions
+	schema  string
+	files   *rollingFile
+}
+
+func NewAvroSink(options FileSinkOptions) (Sink, error) {
+
+	var sink *avroSink
+
+	schema, err := avroSchema(options)
+	if err != nil {
+		return sink, err
+	}
+
+	codec := ocf.Snappy
+	switch options.Compression {
+	case "none":
+		codec = ocf.Null
+	case "deflate":
+		codec = ocf.Deflate
+	}
+
+	sink = &avroSink{
+		Options: options,
+		schema:  schema,
+	}
+	files, err := newRollingFile(options.Directory, options.FilePrefix, ".avro", options.MaxFileBytes, options.MaxFileAge, func(w io.Writer) (rowEncoder, error) {
+		encoder, err := ocf.NewEncoder(schema, w, ocf.WithCodec(codec), ocf.WithBlockLength(options.BlockRows))
+		if err != nil {
+			return nil, err
+		}
+		return &avroEncoder{sink: sink, encoder: encoder}, nil
+	})
+	if err != nil {
+		return sink, err
+	}
+	sink.files = files
+	err = uploadCompletedFiles(files, options, "application/avro")
+	if err != nil {
+		files.Close()
+		return sink, err
+	}
+	return sink, nil
+}
+
+func avroSchema(options FileSinkOptions) (string, error) {
+	fields := []map[string]interface{}{}
+	for _, column := range options.Columns {
+		var t interface{}
+		switch column.Type {
+		case "int64":
+			t = "long"
+		case "float64":
+			t = "double"
+		case "bool":
+			t = "boolean"
+		case "timestamp":
+			t = map[string]string{"type": "long", "logicalType": "timestamp-micros"}
+		default:
+			t = "string"
+		}
+		fields = append(fields, map[string]interface{}{
+			"name":    column.Name,
+			"type":    []interface{}{"null", t},
+			"default": nil,
+		})
+	}
+
+	schema, err := json.Marshal(map[string]interface{}{
+		"type":   "record",
+		"name":   "WebhookEvent",
+		"fields": fields,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error building avro schema: %v", err)
+	}
+	return string(schema), nil
+}
+
+type avroEncoder struct {
+	sink    *avroSink
+	encoder *ocf.Encoder
+}
+
+func (e *avroEncoder) Write(values []interface{}) error {
+	record := make(map[string]interface{}, len(values))
+	for k, column := range e.sink.Options.Columns {
+		record[column.Name] = values[k]
+	}
+	return e.encoder.Encode(record)
+}
+
+func (e *avroEncoder) Close() error {
+	return e.encoder.Close()
+}
+
+func (sink *avroSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	values := make([][]interface{}, len(rows))
+	for k, row := range rows {
+		values[k] = extractColumns(row, sink.Options.Columns)
+	}
+	return sink.files.Write(values)
+}
+
+func (sink *avroSink) Flush(ctx context.Context) error {
+	err := sink.files.Flush()
+	if err != nil {
+		return fmt.Errorf("Error flushing avro file: %v", err)
+	}
+	return nil
+}
+
+func (sink *avroSink) Health(ctx context.Context) error {
+	return sink.files.Health(ctx)
+}
+
+func (sink *avroSink) Close() error {
+	err := sink.files.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing avro file: %v", err)
+	}
+	return nil
+}
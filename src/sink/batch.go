@@ -0,0 +1,234 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// batch holds newline-delimited rows waiting to be written as a single object
+type batch struct {
+	data   bytes.Buffer
+	rows   int
+	opened time.Time
+}
+
+// batcher accumulates rows per partition and hands a batch to the flush
+// function once it grows past maxBytes or gets older than maxAge.
+/*
+	A batch is taken out of the map before it is flushed, so uploads run
+	without the lock and writers to other partitions don't wait on them.
+	Uploads use the batcher's own context: a batch carries rows from many
+	requests, so one client going away must not fail it. Flush is called on
+	shutdown rather than for a request, so its context bounds the uploads
+	too.
+
+	Delivery is at-least-once: a batch that fails to flush is put back, ahead
+	of the rows added since, and retried on the next write or tick. So the
+	same rows may end up in two objects. A partition retains at most
+	retainedBatches times maxBytes; a failed batch that would go over it is
+	dropped, with an error.
+*/
+const (
+	batchUploadTimeout = 2 * time.Minute
+	retainedBatches    = 4
+)
+
+type batcher struct {
+	mu       sync.Mutex
+	maxBytes int
+	maxAge   time.Duration
+	flush    func(ctx context.Context, partition string, b *batch) error
+	batches  map[string]*batch
+
+	ctx       context.Context // For uploads, cancelled once closed
+	cancel    context.CancelFunc
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newBatcher(maxBytes int, maxAge time.Duration, flush func(context.Context, string, *batch) error) *batcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &batcher{
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		flush:    flush,
+		batches:  map[string]*batch{},
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	// Check for aged batches a few times per maxAge, but not more than once a second
+	interval := maxAge / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	b.wg.Add(1)
+	go b.run(interval)
+
+	return b
+}
+
+func (b *batcher) run(interval time.Duration) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			err := b.flushOlderThan(context.Background(), b.maxAge)
+			if err != nil {
+				log.Printf("Failed to flush aged batches: %v", err)
+			}
+		}
+	}
+}
+
+// Add appends a row to its partition's batch, flushing the batch if it is
+// full. The flush doesn't use ctx, since the batch holds other requests' rows.
+func (b *batcher) Add(ctx context.Context, partition string, row []byte) error {
+	b.mu.Lock()
+	current, ok := b.batches[partition]
+	if !ok {
+		current = &batch{opened: time.Now()}
+		b.batches[partition] = current
+	}
+	current.data.Write(row)
+	current.data.WriteByte('\n')
+	current.rows++
+
+	if current.data.Len() < b.maxBytes {
+		b.mu.Unlock()
+		return nil
+	}
+	delete(b.batches, partition)
+	b.mu.Unlock()
+
+	return b.upload(context.Background(), partition, current)
+}
+
+// Flush flushes every pending batch, whatever its size or age
+func (b *batcher) Flush(ctx context.Context) error {
+	return b.flushOlderThan(ctx, 0)
+}
+
+func (b *batcher) flushOlderThan(ctx context.Context, age time.Duration) error {
+	// Take the batches out, then upload them without the lock
+	taken := map[string]*batch{}
+	b.mu.Lock()
+	for partition, current := range b.batches {
+		if time.Since(current.opened) < age {
+			continue
+		}
+		taken[partition] = current
+		delete(b.batches, partition)
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for partition, current := range taken {
+		err := b.upload(ctx, partition, current)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// upload flushes a batch taken out of the map, and puts it back on failure.
+// The upload is cancelled with parent, or once the batcher is closed.
+func (b *batcher) upload(parent context.Context, partition string, current *batch) error {
+	ctx, cancel := context.WithTimeout(parent, batchUploadTimeout)
+	defer cancel()
+	stop := context.AfterFunc(b.ctx, cancel)
+	defer stop()
+
+	err := b.flush(ctx, partition, current)
+	if err == nil {
+		return nil
+	}
+	if !b.retain(partition, current) {
+		return fmt.Errorf("Failed to flush batch for partition %s, dropped its %d rows: %v", partition, current.rows, err)
+	}
+	return fmt.Errorf("Failed to flush batch for partition %s: %v", partition, err)
+}
+
+// retain puts a batch that failed to flush back ahead of the rows added
+// since. It returns false, keeping only the newer rows, when the partition
+// would go over its retention limit.
+func (b *batcher) retain(partition string, failed *batch) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	newer, ok := b.batches[partition]
+	size := failed.data.Len()
+	if ok {
+		size += newer.data.Len()
+	}
+	if size > retainedBatches*b.maxBytes {
+		return false
+	}
+	if ok {
+		failed.data.Write(newer.data.Bytes())
+		failed.rows += newer.rows
+	}
+	b.batches[partition] = failed
+	return true
+}
+
+// Close stops the background flusher and flushes every pending batch. Later
+// calls return the result of the first one.
+func (b *batcher) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+		b.closeErr = b.flushOlderThan(context.Background(), 0)
+		b.cancel()
+	})
+	return b.closeErr
+}
+
+// Helpers
+
+// compress encodes a batch with the configured compression
+func compress(data []byte, compression string) ([]byte, error) {
+	switch compression {
+	case "", "none":
+		return data, nil
+	case "gzip":
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(data)
+		if err != nil {
+			return nil, err
+		}
+		err = w.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("Unsupported compression '%s'", compression)
+	}
+}
+
+// compressionExtension is the file suffix for a compression
+func compressionExtension(compression string) string {
+	switch compression {
+	case "gzip":
+		return ".gz"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,210 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func TestBatcherFlushesFullBatches(t *testing.T) {
+
+	flushed := map[string]int{}
+	b := newBatcher(10, time.Hour, func(ctx context.Context, partition string, current *batch) error {
+		flushed[partition] += current.rows
+		return nil
+	})
+
+	// An 8 byte line stays below the limit, the second one crosses it
+	if err := b.Add(context.Background(), "a", []byte("{\"x\":1}")); err != nil {
+		t.Fatalf("Failed to add row: %v", err)
+	}
+	if flushed["a"] != 0 {
+		t.Fatalf("Batch flushed before reaching max bytes")
+	}
+	if err := b.Add(context.Background(), "a", []byte("{\"x\":2}")); err != nil {
+		t.Fatalf("Failed to add row: %v", err)
+	}
+	if flushed["a"] != 2 {
+		t.Fatalf("Expected 2 flushed rows for partition a, got %d", flushed["a"])
+	}
+
+	// Pending batches are flushed on close
+	if err := b.Add(context.Background(), "b", []byte("{}")); err != nil {
+		t.Fatalf("Failed to add row: %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("Failed to close batcher: %v", err)
+	}
+	if flushed["b"] != 1 {
+		t.Fatalf("Expected 1 flushed row for partition b, got %d", flushed["b"])
+	}
+
+	// Closing again is a no-op
+	if err := b.Close(); err != nil {
+		t.Fatalf("Failed to close batcher twice: %v", err)
+	}
+	if flushed["b"] != 1 {
+		t.Fatalf("Expected 1 flushed row for partition b, got %d", flushed["b"])
+	}
+}
+
+func TestBatcherUploadsOutsideTheLock(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	b := newBatcher(1, time.Hour, func(ctx context.Context, partition string, current *batch) error {
+		if partition == "slow" {
+			close(started)
+			<-unblock
+		}
+		return ctx.Err()
+	})
+	defer b.Close()
+
+	// The writer that fills a batch goes away, the upload carries on
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := make(chan error)
+	go func() {
+		slow <- b.Add(ctx, "slow", []byte("{}"))
+	}()
+	<-started
+	cancel()
+
+	// Other partitions don't wait for the slow upload
+	added := make(chan error)
+	go func() {
+		added <- b.Add(context.Background(), "fast", []byte("{}"))
+	}()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatalf("Failed to add row: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Add blocked behind another partition's upload")
+	}
+
+	close(unblock)
+	if err := <-slow; err != nil {
+		t.Fatalf("Expected the upload to ignore the cancelled request, got %v", err)
+	}
+}
+
+func TestBatcherFlushHonorsItsContext(t *testing.T) {
+	blocked := true
+	b := newBatcher(1024, time.Hour, func(ctx context.Context, partition string, current *batch) error {
+		if blocked {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+
+	if err := b.Add(context.Background(), "a", []byte("{}")); err != nil {
+		t.Fatalf("Failed to add row: %v", err)
+	}
+
+	// A shutdown deadline bounds the upload
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	flushed := make(chan error)
+	go func() {
+		flushed <- b.Flush(ctx)
+	}()
+	select {
+	case err := <-flushed:
+		if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+			t.Fatalf("Expected the flush to fail with the deadline, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Flush ignored its context")
+	}
+
+	// The batch was put back and goes out on close
+	blocked = false
+	if err := b.Close(); err != nil {
+		t.Fatalf("Failed to close batcher: %v", err)
+	}
+}
+
+func TestBatcherRetainsFailedBatches(t *testing.T) {
+	fail := true
+	var flushed []string
+	b := newBatcher(10, time.Hour, func(ctx context.Context, partition string, current *batch) error {
+		if fail {
+			return errors.New("unavailable")
+		}
+		flushed = append(flushed, current.data.String())
+		return nil
+	})
+	defer b.Close()
+
+	// The failed batch is retried along with the rows added since
+	if err := b.Add(context.Background(), "a", []byte("{\"x\":1}__")); err == nil {
+		t.Fatal("Expected the failed flush to be reported")
+	}
+	fail = false
+	if err := b.Add(context.Background(), "a", []byte("{\"x\":2}")); err != nil {
+		t.Fatalf("Failed to add row: %v", err)
+	}
+	if len(flushed) != 1 || flushed[0] != "{\"x\":1}__\n{\"x\":2}\n" {
+		t.Fatalf("Expected the retained rows first in one batch, got %q", flushed)
+	}
+
+	// Past the retention limit, failed batches are dropped
+	fail = true
+	large := []byte(strings.Repeat("x", retainedBatches*10))
+	err := b.Add(context.Background(), "a", large)
+	if err == nil || !strings.Contains(err.Error(), "dropped its 1 rows") {
+		t.Fatalf("Expected the oversized batch to be dropped, got %v", err)
+	}
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Expected nothing left to flush, got %v", err)
+	}
+}
+
+func TestBatchParamsMustBePositive(t *testing.T) {
+	for _, param := range []string{"max_batch_bytes", "max_batch_age_seconds"} {
+		_, err := conf.NewSink("s3", map[string]interface{}{"bucket": "events", param: 0})
+		if err == nil {
+			t.Fatalf("Expected %s 0 to be rejected", param)
+		}
+	}
+}
+
+func TestS3Partition(t *testing.T) {
+	sink := &s3Sink{Prefix: "archive"}
+	receivedAt := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	got := sink.partition("abc", receivedAt)
+	want := "archive/source_id=abc/dt=2024-02-03/hour=04"
+	if got != want {
+		t.Fatalf("Expected partition %s, got %s", want, got)
+	}
+}
+
+func TestCompressGzip(t *testing.T) {
+	data := []byte(strings.Repeat("{\"event\":\"{}\"}\n", 10))
+
+	compressed, err := compress(data, "gzip")
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Failed to open gzip reader: %v", err)
+	}
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if !bytes.Equal(data, decompressed) {
+		t.Fatal("Decompressed data does not match the original")
+	}
+}
@@ -0,0 +1,135 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "bigquery",
+		Description: "Appends events to a BigQuery table through the Storage Write API.",
+		Params: []conf.Param{
+			{Name: "project", Type: conf.ParamString, Required: true},
+			{Name: "dataset", Type: conf.ParamString, Required: true},
+			{Name: "table", Type: conf.ParamString, Required: true},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewBigQuerySink(
+			config.Config["project"].(string),
+			config.Config["dataset"].(string),
+			config.Config["table"].(string),
+			"webhook-connector",
+		)
+	})
+}
+
+// BigQuery Sink
+type bigQuerySink struct {
+	Project string
+	Dataset string
+	Table string
+	Trace string
+	client *managedwriter.Client
+	stream *managedwriter.ManagedStream
+}
+
+func NewBigQuerySink( project string, dataset string, table string, trace string) (Sink, error) {
+	
+	var sink *bigQuerySink
+
+	// Create bigquery client
+	client, err := managedwriter.NewClient(context.Background(), project)
+	if err != nil {
+		return sink, fmt.Errorf("Error creating Bigquery Writer: %v", err)
+	}
+	
+
+	// Get the descriptor for the event message
+	/*
+		When I want to generalize to different many schemas...
+		Create a descriptor based on the schema configuration.
+	*/
+	m := &model.WebhookEvent{}
+	descriptor, err := adapt.NormalizeDescriptor(m.ProtoReflect().Descriptor())
+	if err != nil {
+		return sink, fmt.Errorf("Failed to get prot descriptor: %v", err)
+	}
+
+	// Create managed stream
+	tableName := fmt.Sprintf("projects/%s/datasets/%s/tables/%s", project, dataset, table)
+	stream, err := client.NewManagedStream(
+		context.Background(),
+		managedwriter.WithDestinationTable(tableName),
+		managedwriter.WithSchemaDescriptor(descriptor),
+	)
+	if err != nil {
+		return sink, fmt.Errorf("Failed to create managed stream: %v", err)
+	}
+
+	// Construct the sink object
+	sink = &bigQuerySink{
+		Project: project,
+		Dataset: dataset,
+		Table: table,
+		Trace: trace,
+		client: client,
+		stream: stream,
+	}
+
+	return sink, nil
+}
+
+
+
+func (sink *bigQuerySink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+
+	// Encode the messages
+	encoded := make([][]byte, len(rows))
+	for k, v := range rows {
+		b, err := proto.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("Error marshalling rows: %v", err)
+		}
+		encoded[k] = b
+	}
+
+	result, err := sink.stream.AppendRows(ctx, encoded)
+	if err != nil {
+		return fmt.Errorf("Error appending rows: %v", err)
+	}
+	_, err = result.GetResult(ctx)
+	if err != nil {
+		return fmt.Errorf("Error appending rows: %v", err)
+	}
+
+	return nil
+}
+func (sink *bigQuerySink) Flush(ctx context.Context) error {
+	// Appends are acknowledged before WriteRows returns, nothing is buffered
+	return nil
+}
+func (sink *bigQuerySink) Health(ctx context.Context) error {
+	_, err := sink.client.GetWriteStream(ctx, &storagepb.GetWriteStreamRequest{Name: sink.stream.StreamName()})
+	if err != nil {
+		return fmt.Errorf("Error getting write stream: %v", err)
+	}
+	return nil
+}
+func (sink *bigQuerySink) Close() error {
+
+	err := sink.client.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing client: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/parquet-go/parquet-go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func columnarTestOptions(t *testing.T) FileSinkOptions {
+	columns := append([]conf.Column{}, DefaultColumns...)
+	columns = append(columns,
+		conf.Column{Name: "amount", Path: "event.amount", Type: "float64"},
+		conf.Column{Name: "quantity", Path: "event.quantity", Type: "int64"},
+	)
+	return FileSinkOptions{
+		Directory:  t.TempDir(),
+		FilePrefix: "events",
+		Columns:    columns,
+		MaxFileAge: time.Hour,
+		BlockRows:  10,
+	}
+}
+
+func columnarTestRows() []protoreflect.ProtoMessage {
+	return []protoreflect.ProtoMessage{
+		&model.WebhookEvent{
+			Metadata: &model.Metadata{SourceId: "abc", ReceivedAt: timestamppb.Now()},
+			Event:    `{"amount": 1.5, "quantity": 9007199254740993}`,
+		},
+		&model.WebhookEvent{Event: `{}`},
+	}
+}
+
+// writeAndFind writes the test rows, closes the sink and returns the single completed file
+func writeAndFind(t *testing.T, sink Sink, directory string, pattern string) string {
+	err := sink.WriteRows(context.Background(), columnarTestRows())
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(directory, pattern))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected a single completed file, got %v (%v)", files, err)
+	}
+	return files[0]
+}
+
+func TestParquetSink(t *testing.T) {
+	options := columnarTestOptions(t)
+	sink, err := NewParquetSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	path := writeAndFind(t, sink, options.Directory, "events-*.parquet")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	info, _ := f.Stat()
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatalf("Failed to read parquet file: %v", err)
+	}
+	if file.NumRows() != 2 {
+		t.Fatalf("Expected 2 rows, got %d", file.NumRows())
+	}
+
+	reader := parquet.NewReader(file)
+	row := map[string]interface{}{}
+	err = reader.Read(&row)
+	if err != nil {
+		t.Fatalf("Failed to read row: %v", err)
+	}
+	// Integers above 2^53 keep every digit
+	if row["source_id"] != "abc" || row["amount"] != 1.5 || row["quantity"] != int64(9007199254740993) {
+		t.Fatalf("Unexpected row %v", row)
+	}
+}
+
+func TestAvroSink(t *testing.T) {
+	options := columnarTestOptions(t)
+	sink, err := NewAvroSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	path := writeAndFind(t, sink, options.Directory, "events-*.avro")
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	decoder, err := ocf.NewDecoder(f)
+	if err != nil {
+		t.Fatalf("Failed to read avro file: %v", err)
+	}
+
+	var records []map[string]interface{}
+	for decoder.HasNext() {
+		var record map[string]interface{}
+		err = decoder.Decode(&record)
+		if err != nil {
+			t.Fatalf("Failed to decode record: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0]["source_id"] != "abc" || records[0]["amount"] != 1.5 || records[1]["amount"] != nil || records[0]["quantity"] != int64(9007199254740993) {
+		t.Fatalf("Unexpected records %v", records)
+	}
+	if _, ok := records[0]["received_at"].(time.Time); !ok {
+		t.Fatalf("Expected received_at to decode as a timestamp, got %T", records[0]["received_at"])
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	options := CSVSinkOptions{
+		FileSinkOptions: columnarTestOptions(t),
+		Delimiter:       ';',
+		Quote:           "all",
+		Header:          true,
+	}
+	options.Columns = []conf.Column{
+		{Name: "source_id", Path: "source_id", Type: "string"},
+		{Name: "amount", Path: "event.amount", Type: "float64"},
+		{Name: "event", Path: "event", Type: "string"},
+	}
+	sink, err := NewCSVSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	path := writeAndFind(t, sink, options.Directory, "events-*.csv")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	want := `"source_id";"amount";"event"
+"abc";"1.5";"{""amount"": 1.5, ""quantity"": 9007199254740993}"
+"";"";"{}"
+`
+	if string(data) != want {
+		t.Fatalf("Unexpected csv file:\n%s", data)
+	}
+}
+
+func TestRollingFileFlush(t *testing.T) {
+	options := columnarTestOptions(t)
+	sink, err := NewCSVSink(CSVSinkOptions{FileSinkOptions: options, Delimiter: ',', Quote: "minimal", Header: true})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	ctx := context.Background()
+	if err := sink.Health(ctx); err != nil {
+		t.Fatalf("Expected a healthy sink, got %v", err)
+	}
+	if err := sink.WriteRows(ctx, columnarTestRows()); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if err := sink.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	// Flushing completes the file without closing the sink
+	files, _ := filepath.Glob(filepath.Join(options.Directory, "events-*.csv"))
+	if len(files) != 1 {
+		t.Fatalf("Expected a completed file after flush, got %v", files)
+	}
+	if err := sink.WriteRows(ctx, columnarTestRows()); err != nil {
+		t.Fatalf("Failed to write rows after flush: %v", err)
+	}
+}
+
+func TestCSVSinkFilePerDay(t *testing.T) {
+	options := CSVSinkOptions{FileSinkOptions: columnarTestOptions(t), Delimiter: ',', Quote: "minimal"}
+	options.MaxFileAge = 0
+	options.Columns = []conf.Column{{Name: "event", Path: "event", Type: "string"}}
+	sink, err := NewCSVSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+
+	at := func(day int, hour int, event string) protoreflect.ProtoMessage {
+		receivedAt := time.Date(2024, 2, day, hour, 0, 0, 0, time.UTC)
+		return &model.WebhookEvent{Metadata: &model.Metadata{ReceivedAt: timestamppb.New(receivedAt)}, Event: event}
+	}
+	rows := []protoreflect.ProtoMessage{at(3, 22, "a"), at(3, 23, "b"), at(4, 0, "c")}
+	if err := sink.WriteRows(context.Background(), rows); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	for day, want := range map[string]string{"2024-02-03": "a\nb\n", "2024-02-04": "c\n"} {
+		files, _ := filepath.Glob(filepath.Join(options.Directory, "events-"+day+"-*.csv"))
+		if len(files) != 1 {
+			t.Fatalf("Expected a single file for %s, got %v", day, files)
+		}
+		data, _ := os.ReadFile(files[0])
+		if string(data) != want {
+			t.Fatalf("Expected %q in the file of %s, got %q", want, day, data)
+		}
+	}
+}
+
+func TestCSVFieldEscapesFormulas(t *testing.T) {
+	cases := map[interface{}]string{
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-1":                "'-1",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\tcmd":             "'\tcmd",
+		"plain":             "plain",
+		"a=b":               "a=b",
+		"":                  "",
+		int64(-5):           "-5",
+		float64(-1.5):       "-1.5",
+	}
+	for value, want := range cases {
+		if got := csvField(value); got != want {
+			t.Errorf("Expected %q for %v, got %q", want, value, got)
+		}
+	}
+}
+
+func TestRollingFileRemovesLeftovers(t *testing.T) {
+	options := columnarTestOptions(t)
+	plant := func(name string) string {
+		path := filepath.Join(options.Directory, name)
+		if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	leftover := plant(".events-20240101T000000.000000000-1a2b3c4d.parquet.inprogress")
+	others := []string{
+		plant(".events-orders-20240101T000000.000000000-1a2b3c4d.parquet.inprogress"), // Another sink's prefix
+		plant(".events-20240101T000000.000000000-1a2b3c4d.avro.inprogress"),           // Another sink's format
+		plant("events-20240101T000000.000000000-1a2b3c4d.parquet"),                    // Completed
+	}
+
+	sink, err := NewParquetSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Fatalf("Expected the incomplete file to be removed, got %v", err)
+	}
+	for _, path := range others {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("Expected %s to be kept, got %v", path, err)
+		}
+	}
+
+	// A second sink on the directory leaves the file being written alone
+	if err := sink.WriteRows(context.Background(), columnarTestRows()); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	other, err := NewParquetSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	other.Close()
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatalf("Expected the first sink's file to survive, got %v", err)
+	}
+}
+
+// fakeUploader keeps uploaded objects in memory and fails while failing is set
+type fakeUploader struct {
+	mu      sync.Mutex
+	failing bool
+	closed  bool
+	objects map[string]string
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, name string, contentType string, data io.Reader, size int64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.failing {
+		return errors.New("bucket unreachable")
+	}
+	u.objects[name] = contentType
+	return nil
+}
+func (u *fakeUploader) Health(ctx context.Context) error { return nil }
+func (u *fakeUploader) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.closed = true
+	return nil
+}
+
+func TestRollingFileUploads(t *testing.T) {
+	options := columnarTestOptions(t)
+	uploader := &fakeUploader{failing: true, objects: map[string]string{}}
+	sink, err := NewParquetSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	sink.(*parquetSink).files.startUploads(uploader, "lake/events", "application/vnd.apache.parquet")
+
+	ctx := context.Background()
+	if err := sink.WriteRows(ctx, columnarTestRows()); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if err := sink.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := sink.WriteRows(ctx, columnarTestRows()); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	// Failed uploads keep the files for the next attempt
+	if err := sink.Close(); err == nil {
+		t.Fatal("Expected closing with a failing upload to report it")
+	}
+	files, _ := filepath.Glob(filepath.Join(options.Directory, "events-*.parquet"))
+	if len(files) != 2 {
+		t.Fatalf("Expected both files kept after a failed upload, got %v", files)
+	}
+
+	// The next sink on the directory uploads and removes them
+	uploader.failing = false
+	sink, err = NewParquetSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	sink.(*parquetSink).files.startUploads(uploader, "lake/events", "application/vnd.apache.parquet")
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	for _, file := range files {
+		if uploader.objects["lake/events/"+filepath.Base(file)] != "application/vnd.apache.parquet" {
+			t.Fatalf("Expected %s to be uploaded, got %v", filepath.Base(file), uploader.objects)
+		}
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed after upload", file)
+		}
+	}
+}
+
+func TestParseUploadTo(t *testing.T) {
+	cases := []struct {
+		uploadTo string
+		scheme   string
+		bucket   string
+		prefix   string
+		valid    bool
+	}{
+		{"", "", "", "", true},
+		{"gs://lake", "gs", "lake", "", true},
+		{"s3://lake/raw/events/", "s3", "lake", "raw/events", true},
+		{"https://lake/raw", "", "", "", false},
+		{"gs:///raw", "", "", "", false},
+	}
+	for _, c := range cases {
+		scheme, bucket, prefix, err := parseUploadTo(c.uploadTo)
+		if (err == nil) != c.valid || scheme != c.scheme || bucket != c.bucket || prefix != c.prefix {
+			t.Errorf("Unexpected %q, %q, %q (%v) for %q", scheme, bucket, prefix, err, c.uploadTo)
+		}
+	}
+}
+
+func TestExtractColumnsKeepsLargeIntegers(t *testing.T) {
+	columns := []conf.Column{
+		{Name: "id", Path: "event.id", Type: "int64"},
+		{Name: "id_text", Path: "event.id", Type: "string"},
+		{Name: "overflow", Path: "event.overflow", Type: "int64"},
+		{Name: "whole", Path: "event.whole", Type: "int64"},
+		{Name: "amount", Path: "event.amount", Type: "float64"},
+	}
+	event := &model.WebhookEvent{Event: `{"id": 9007199254740993, "overflow": 9223372036854775808, "whole": 1e3, "amount": 1.5}`}
+	values := extractColumns(event, columns)
+	want := []interface{}{int64(9007199254740993), "9007199254740993", nil, int64(1000), 1.5}
+	for k := range want {
+		if values[k] != want[k] {
+			t.Errorf("Expected %v (%T) in column %s, got %v (%T)", want[k], want[k], columns[k].Name, values[k], values[k])
+		}
+	}
+
+	// A malformed payload has no payload columns
+	for _, payload := range []string{`{"id": 1`, `{"id": 1} {"id": 2}`} {
+		values = extractColumns(&model.WebhookEvent{Event: payload}, columns)
+		for k, value := range values {
+			if value != nil {
+				t.Errorf("Expected no value in column %s of %q, got %v", columns[k].Name, payload, value)
+			}
+		}
+	}
+}
+
+func TestCSVSinkLargeIntegers(t *testing.T) {
+	options := CSVSinkOptions{FileSinkOptions: columnarTestOptions(t), Delimiter: ',', Quote: "minimal"}
+	options.Columns = []conf.Column{{Name: "id", Path: "event.id", Type: "int64"}, {Name: "ref", Path: "event.ref", Type: "string"}}
+	sink, err := NewCSVSink(options)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{
+		&model.WebhookEvent{Event: `{"id": 9007199254740993, "ref": 9007199254740993}`},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(options.Directory, "events-*.csv"))
+	if len(files) != 1 {
+		t.Fatalf("Expected a single file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if string(data) != "9007199254740993,9007199254740993\n" {
+		t.Fatalf("Unexpected csv file %q", data)
+	}
+}
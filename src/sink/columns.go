@@ -0,0 +1,193 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+	"github.com/altxtech/webhook-connector/src/utils"
+)
+
+// Tabular sinks
+/*
+	Columnar and delimited sinks write rows of typed columns. Without a
+	configured schema they use the WebhookEvent fields as columns.
+*/
+var DefaultColumns = []conf.Column{
+	{Name: "source_id", Path: "source_id", Type: "string"},
+	{Name: "source_name", Path: "source_name", Type: "string"},
+	{Name: "received_at", Path: "received_at", Type: "timestamp"},
+	{Name: "loaded_at", Path: "loaded_at", Type: "timestamp"},
+	{Name: "event", Path: "event", Type: "string"},
+}
+
+// extractColumns returns the value of every column for a row. Values are
+// string, int64, float64, bool or time.Time according to the column type,
+// or nil when the path is missing or can't be converted. Numbers are decoded
+// as json.Number, so integers above 2^53 keep every digit. Payload columns
+// of an event whose payload isn't valid JSON are all nil.
+func extractColumns(row protoreflect.ProtoMessage, columns []conf.Column) []interface{} {
+	values := make([]interface{}, len(columns))
+	event, ok := row.(*model.WebhookEvent)
+	if !ok {
+		return values
+	}
+
+	// Only decode the payload if a column needs it
+	var payload interface{}
+	var payloadErr error
+	decoded := false
+
+	for k, column := range columns {
+		var raw interface{}
+		switch column.Path {
+		case "source_id":
+			raw = event.GetMetadata().GetSourceId()
+		case "source_name":
+			raw = event.GetMetadata().GetSourceName()
+		case "received_at":
+			if ts := event.GetMetadata().GetReceivedAt(); ts != nil {
+				raw = ts.AsTime()
+			}
+		case "loaded_at":
+			if ts := event.GetMetadata().GetLoadedAt(); ts != nil {
+				raw = ts.AsTime()
+			}
+		case "event":
+			raw = event.GetEvent()
+		default:
+			if !decoded {
+				payload, payloadErr = decodePayload(event.GetEvent())
+				decoded = true
+			}
+			if payloadErr == nil {
+				raw, _ = utils.LookupPath(payload, strings.TrimPrefix(column.Path, "event."))
+			}
+		}
+		values[k] = convertColumn(raw, column.Type)
+	}
+	return values
+}
+
+// decodePayload decodes a JSON payload, keeping numbers as json.Number
+func decodePayload(data string) (interface{}, error) {
+	var payload interface{}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&payload)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("Unexpected data after the payload")
+	}
+	return payload, nil
+}
+
+// numberToInt64 converts a JSON number holding a whole value that fits in an int64
+func numberToInt64(n json.Number) (interface{}, bool) {
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	if err == nil {
+		return i, true
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return nil, false
+	}
+	// E.g. 1.0 or 1e3
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil, false
+	}
+	return int64(f), true
+}
+
+func convertColumn(raw interface{}, columnType string) interface{} {
+	if raw == nil {
+		return nil
+	}
+	switch columnType {
+	case "string":
+		if t, ok := raw.(time.Time); ok {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+		return utils.JSONValueString(raw)
+	case "int64":
+		switch v := raw.(type) {
+		case json.Number:
+			i, ok := numberToInt64(v)
+			if !ok {
+				return nil
+			}
+			return i
+		case float64:
+			if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return nil
+			}
+			return int64(v)
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil
+			}
+			return i
+		}
+	case "float64":
+		switch v := raw.(type) {
+		case json.Number:
+			f, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return nil
+			}
+			return f
+		case float64:
+			return v
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil
+			}
+			return f
+		}
+	case "bool":
+		switch v := raw.(type) {
+		case bool:
+			return v
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil
+			}
+			return b
+		}
+	case "timestamp":
+		// RFC 3339 strings or unix epoch seconds
+		switch v := raw.(type) {
+		case time.Time:
+			return v.UTC()
+		case json.Number:
+			f, err := strconv.ParseFloat(string(v), 64)
+			if err != nil {
+				return nil
+			}
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		case float64:
+			sec, frac := math.Modf(v)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil
+			}
+			return t.UTC()
+		}
+	}
+	return nil
+}
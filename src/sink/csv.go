@@ -0,0 +1,205 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/utils"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "csv",
+		Description: "Writes a CSV file of columns per UTC day into a local directory.",
+		Params: append(fileSinkParams(0),
+			conf.Param{Name: "delimiter", Type: conf.ParamString, Default: ","},
+			conf.Param{Name: "quote", Type: conf.ParamString, Default: "minimal", Options: []string{"minimal", "all"}},
+			conf.Param{Name: "header", Type: conf.ParamBool, Default: true},
+		),
+		Validate: func(s conf.Sink) error {
+			delimiter := s.StringParam("delimiter", ",")
+			if utf8.RuneCountInString(delimiter) != 1 || strings.ContainsAny(delimiter, "\"\r\n") {
+				return errors.New("Parameter delimiter must be a single character other than a quote or newline.")
+			}
+			return nil
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewCSVSink(CSVSinkOptions{
+			FileSinkOptions: fileSinkOptions(config),
+			Delimiter:       []rune(config.StringParam("delimiter", ""))[0],
+			Quote:           config.StringParam("quote", ""),
+			Header:          config.BoolParam("header", false),
+		})
+	})
+}
+
+// CSV file Sink
+/*
+	Writes CSV files of columns (see columns.go) into a directory, one per
+	UTC day of the events' received_at, e.g. events-2024-02-03-1a2b3c4d.csv.
+	max_file_bytes and max_file_age_seconds can split a day further.
+
+	Quote "minimal" only quotes fields that need it, "all" quotes every field.
+	Missing values are written as empty fields and timestamps as RFC 3339.
+	Text starting with = + - @, a tab or a carriage return is prefixed with a
+	single quote, so spreadsheets don't evaluate it as a formula.
+*/
+type CSVSinkOptions struct {
+	FileSinkOptions
+	Delimiter rune
+	Quote     string // "minimal" or "all"
+	Header    bool
+}
+
+type csvSink struct {
+	Options CSVSinkOptions
+	files   *rollingFile
+}
+
+func NewCSVSink(options CSVSinkOptions) (Sink, error) {
+	sink := &csvSink{Options: options}
+	files, err := newRollingFile(options.Directory, options.FilePrefix, ".csv", options.MaxFileBytes, options.MaxFileAge, sink.newEncoder)
+	if err != nil {
+		return sink, err
+	}
+	sink.files = files
+	return sink, nil
+}
+
+type csvEncoder struct {
+	options CSVSinkOptions
+	w       *bufio.Writer
+	csv     *csv.Writer
+}
+
+func (sink *csvSink) newEncoder(w io.Writer) (rowEncoder, error) {
+	e := &csvEncoder{options: sink.Options, w: bufio.NewWriter(w)}
+	e.csv = csv.NewWriter(e.w)
+	e.csv.Comma = sink.Options.Delimiter
+
+	if sink.Options.Header {
+		header := make([]string, len(sink.Options.Columns))
+		for k, column := range sink.Options.Columns {
+			header[k] = column.Name
+		}
+		err := e.writeRecord(header)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *csvEncoder) writeRecord(record []string) error {
+	if e.options.Quote != "all" {
+		return e.csv.Write(record)
+	}
+
+	// encoding/csv only quotes when needed, so quote everything by hand
+	for k, field := range record {
+		if k > 0 {
+			e.w.WriteRune(e.options.Delimiter)
+		}
+		e.w.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`)
+	}
+	_, err := e.w.WriteString("\n")
+	return err
+}
+
+func (e *csvEncoder) Write(values []interface{}) error {
+	record := make([]string, len(values))
+	for k, value := range values {
+		record[k] = csvField(value)
+	}
+	return e.writeRecord(record)
+}
+
+func csvField(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return escapeFormula(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return escapeFormula(utils.JSONValueString(v))
+	}
+}
+
+// escapeFormula keeps spreadsheets from evaluating text as a formula
+func escapeFormula(field string) string {
+	if field != "" && strings.ContainsRune("=+-@\t\r", rune(field[0])) {
+		return "'" + field
+	}
+	return field
+}
+
+func (e *csvEncoder) Close() error {
+	e.csv.Flush()
+	err := e.csv.Error()
+	if err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (sink *csvSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	// Write each run of rows from the same day to that day's file
+	var day string
+	var values [][]interface{}
+	for _, row := range rows {
+		_, receivedAt := rowMetadata(row)
+		rowDay := receivedAt.Format(dayFormat)
+		if rowDay != day && len(values) > 0 {
+			err := sink.files.WriteDay(day, values)
+			if err != nil {
+				return err
+			}
+			values = nil
+		}
+		day = rowDay
+		values = append(values, extractColumns(row, sink.Options.Columns))
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return sink.files.WriteDay(day, values)
+}
+
+func (sink *csvSink) Flush(ctx context.Context) error {
+	err := sink.files.Flush()
+	if err != nil {
+		return fmt.Errorf("Error flushing csv file: %v", err)
+	}
+	return nil
+}
+
+func (sink *csvSink) Health(ctx context.Context) error {
+	return sink.files.Health(ctx)
+}
+
+func (sink *csvSink) Close() error {
+	err := sink.files.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing csv file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package sink
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+// encodeRow renders a row as a single line of JSON
+func encodeRow(row protoreflect.ProtoMessage) ([]byte, error) {
+	msg, err := protojson.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling row: %v", err)
+	}
+	return msg, nil
+}
+
+// rowMetadata returns the source id and receive time of a webhook event.
+// Other messages get an empty source id and the current time.
+func rowMetadata(row protoreflect.ProtoMessage) (string, time.Time) {
+	event, ok := row.(*model.WebhookEvent)
+	if !ok || event.GetMetadata() == nil {
+		return "", time.Now().UTC()
+	}
+	md := event.GetMetadata()
+	if md.GetReceivedAt() == nil {
+		return md.GetSourceId(), time.Now().UTC()
+	}
+	return md.GetSourceId(), md.GetReceivedAt().AsTime().UTC()
+}
+
+// eventID derives a stable id for a row, so retried writes of the same
+// event can be deduplicated by destinations that support it. For webhook
+// events only the source id and the payload count, since the receive and
+// load times change when an event is delivered again.
+func eventID(row protoreflect.ProtoMessage) (string, error) {
+	var encoded []byte
+	if event, ok := row.(*model.WebhookEvent); ok {
+		encoded = append([]byte(event.GetMetadata().GetSourceId()), 0)
+		encoded = append(encoded, event.GetEvent()...)
+	} else {
+		var err error
+		encoded, err = proto.MarshalOptions{Deterministic: true}.Marshal(row)
+		if err != nil {
+			return "", fmt.Errorf("Error marshalling row: %v", err)
+		}
+	}
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:16]), nil
+}
+
+// rowAttributes returns the event metadata as string attributes, for
+// destinations that carry headers or fields next to the payload.
+func rowAttributes(row protoreflect.ProtoMessage) map[string]string {
+	attributes := map[string]string{}
+	event, ok := row.(*model.WebhookEvent)
+	if !ok {
+		return attributes
+	}
+	md := event.GetMetadata()
+	attributes["source_id"] = md.GetSourceId()
+	attributes["source_name"] = md.GetSourceName()
+	if md.GetReceivedAt() != nil {
+		attributes["received_at"] = md.GetReceivedAt().AsTime().Format(time.RFC3339Nano)
+	}
+	return attributes
+}
+
+type requestHeadersKey struct{}
+
+// WithRequestHeaders attaches the headers of the webhook request to the
+// context of a write, for sinks that route on them. They aren't part of the
+// event, so credentials like Authorization never reach a destination.
+func WithRequestHeaders(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, requestHeadersKey{}, header)
+}
+
+// requestHeader returns a header of the webhook request being written, or ""
+func requestHeader(ctx context.Context, name string) string {
+	header, _ := ctx.Value(requestHeadersKey{}).(http.Header)
+	return header.Get(name)
+}
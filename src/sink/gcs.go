@@ -0,0 +1,139 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "gcs",
+		Description: "Writes rolling JSON lines files of events into a Google Cloud Storage bucket.",
+		Params: []conf.Param{
+			{Name: "bucket", Type: conf.ParamString, Required: true},
+			{Name: "object_name", Type: conf.ParamString, Default: DefaultGCSObjectName, Description: "Template, must contain {uuid} or {timestamp}."},
+			{Name: "compression", Type: conf.ParamString, Default: "gzip", Options: []string{"none", "gzip"}},
+			{Name: "max_file_bytes", Type: conf.ParamInteger, Default: 8 << 20, Positive: true},
+			{Name: "max_file_age_seconds", Type: conf.ParamInteger, Default: 300, Positive: true},
+		},
+		Validate: func(s conf.Sink) error {
+			// Rolled files would overwrite each other without a unique part in their name
+			objectName := s.StringParam("object_name", DefaultGCSObjectName)
+			if !strings.Contains(objectName, "{uuid}") && !strings.Contains(objectName, "{timestamp}") {
+				return errors.New("Parameter object_name must contain {uuid} or {timestamp}.")
+			}
+			return nil
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewGCSSink(
+			config.Config["bucket"].(string),
+			config.StringParam("object_name", ""),
+			config.StringParam("compression", ""),
+			config.IntParam("max_file_bytes", 0),
+			time.Duration(config.IntParam("max_file_age_seconds", 0))*time.Second,
+		)
+	})
+}
+
+// Google Cloud Storage Sink
+/*
+	Writes rolling newline-delimited JSON files into a bucket. A file is
+	rolled once it reaches maxFileBytes or gets older than maxFileAge.
+
+	Object names come from a template (see template.go) that may also use
+	{timestamp} and {uuid}, filled in when the file is written. The
+	.jsonl[.gz] extension is always appended.
+
+	Set STORAGE_EMULATOR_HOST to run against a local fake GCS server.
+*/
+const DefaultGCSObjectName = "{source_id}/{yyyy}/{mm}/{dd}/{hh}/{timestamp}-{uuid}"
+
+type gcsSink struct {
+	Bucket      string
+	ObjectName  string
+	Compression string
+	uploader    objectUploader
+	batcher     *batcher
+}
+
+func NewGCSSink(bucket string, objectName string, compression string, maxFileBytes int, maxFileAge time.Duration) (Sink, error) {
+
+	var sink *gcsSink
+
+	uploader, err := newGCSUploader(bucket)
+	if err != nil {
+		return sink, err
+	}
+
+	sink = &gcsSink{
+		Bucket:      bucket,
+		ObjectName:  objectName,
+		Compression: compression,
+		uploader:    uploader,
+	}
+	sink.batcher = newBatcher(maxFileBytes, maxFileAge, sink.writeObject)
+
+	return sink, nil
+}
+
+func (sink *gcsSink) writeObject(ctx context.Context, partition string, b *batch) error {
+	data, err := compress(b.data.Bytes(), sink.Compression)
+	if err != nil {
+		return fmt.Errorf("Error compressing file: %v", err)
+	}
+
+	// The partition is the object name with the row placeholders already rendered
+	name := renderTemplate(partition, map[string]string{
+		"timestamp": strconv.FormatInt(time.Now().UnixNano(), 10),
+		"uuid":      uuid.NewString(),
+	}) + ".jsonl" + compressionExtension(sink.Compression)
+
+	contentType := "application/x-ndjson"
+	if sink.Compression == "gzip" {
+		contentType = "application/gzip"
+	}
+	return sink.uploader.Upload(ctx, name, contentType, bytes.NewReader(data), int64(len(data)))
+}
+
+func (sink *gcsSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	for _, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		partition := renderTemplate(sink.ObjectName, rowPlaceholders(row))
+		err = sink.batcher.Add(ctx, partition, encoded)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sink *gcsSink) Flush(ctx context.Context) error {
+	return sink.batcher.Flush(ctx)
+}
+
+func (sink *gcsSink) Health(ctx context.Context) error {
+	return sink.uploader.Health(ctx)
+}
+
+// Close closes the client even if the pending files fail to flush
+func (sink *gcsSink) Close() error {
+	var flushErr error
+	err := sink.batcher.Close()
+	if err != nil {
+		flushErr = fmt.Errorf("Error flushing pending files: %v", err)
+	}
+	return errors.Join(flushErr, sink.uploader.Close())
+}
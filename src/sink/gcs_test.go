@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func TestRenderObjectName(t *testing.T) {
+	event := &model.WebhookEvent{
+		Metadata: &model.Metadata{
+			SourceId:   "abc",
+			ReceivedAt: timestamppb.New(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)),
+		},
+	}
+
+	got := renderTemplate(DefaultGCSObjectName, rowPlaceholders(event))
+	want := "abc/2024/02/03/04/{timestamp}-{uuid}"
+	if got != want {
+		t.Fatalf("Expected object name %s, got %s", want, got)
+	}
+}
+
+func TestGCSSinkClosesUploaderAfterFailedFlush(t *testing.T) {
+	uploader := &fakeUploader{failing: true, objects: map[string]string{}}
+	sink := &gcsSink{ObjectName: "{source_id}/{uuid}", uploader: uploader}
+	sink.batcher = newBatcher(1<<20, time.Hour, sink.writeObject)
+
+	event := &model.WebhookEvent{Metadata: &model.Metadata{SourceId: "abc"}, Event: "{}"}
+	if err := sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{event}); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	err := sink.Close()
+	if err == nil || !strings.Contains(err.Error(), "bucket unreachable") {
+		t.Fatalf("Expected the failed flush to be reported, got %v", err)
+	}
+	if !uploader.closed {
+		t.Fatal("Expected the uploader to be closed")
+	}
+}
+
+// Runs against a fake GCS server, e.g.
+// docker run -p 4443:4443 fsouza/fake-gcs-server -scheme http
+// STORAGE_EMULATOR_HOST=localhost:4443 go test ./sink
+func TestGCSSink(t *testing.T) {
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		t.Skip("STORAGE_EMULATOR_HOST not set")
+	}
+	ctx := context.Background()
+	bucket := "webhook-connector-test"
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		t.Fatalf("Failed to create storage client: %v", err)
+	}
+	defer client.Close()
+	client.Bucket(bucket).Create(ctx, "test-project", nil) // May already exist
+
+	sink, err := NewGCSSink(bucket, "test/{source_id}/{uuid}", "gzip", 1<<20, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	event := &model.WebhookEvent{
+		Metadata: &model.Metadata{SourceId: "gcs-test", ReceivedAt: timestamppb.Now()},
+		Event:    "{}",
+	}
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{event})
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	it := client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: "test/gcs-test/"})
+	found := false
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to list objects: %v", err)
+		}
+		if strings.HasSuffix(attrs.Name, ".jsonl.gz") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("No file was written to the bucket")
+	}
+}
@@ -0,0 +1,388 @@
+package sink
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "jsonl",
+		Description: "Appends events as JSON lines to a local file, with optional rotation.",
+		Params: []conf.Param{
+			{Name: "file_path", Type: conf.ParamString, Required: true},
+			{Name: "fsync", Type: conf.ParamString, Default: "interval", Options: []string{"always", "interval", "never"}},
+			{Name: "max_file_bytes", Type: conf.ParamInteger, Default: 0, Description: "0 disables size based rotation."},
+			{Name: "max_file_age_seconds", Type: conf.ParamInteger, Default: 0, Description: "0 disables time based rotation."},
+			{Name: "compression", Type: conf.ParamString, Default: "none", Options: []string{"none", "gzip", "zstd"}},
+			{Name: "max_retained_files", Type: conf.ParamInteger, Default: 0, Description: "0 keeps every rotated file."},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewJSONLSink(JSONLSinkOptions{
+			Path:             config.Config["file_path"].(string),
+			Fsync:            config.StringParam("fsync", ""),
+			MaxFileBytes:     int64(config.IntParam("max_file_bytes", 0)),
+			MaxFileAge:       time.Duration(config.IntParam("max_file_age_seconds", 0)) * time.Second,
+			Compression:      config.StringParam("compression", ""),
+			MaxRetainedFiles: config.IntParam("max_retained_files", 0),
+		})
+	})
+}
+
+// Local file sink
+/*
+	Appends events as JSON lines to Path, keeping the file open between writes.
+
+	The active file is rotated once it grows past MaxFileBytes or gets older
+	than MaxFileAge. Rotated segments are renamed to
+	{name}-{timestamp}{ext}, compressed in the background when Compression
+	is set, and the oldest ones are removed beyond MaxRetainedFiles.
+*/
+type JSONLSinkOptions struct {
+	Path             string
+	Fsync            string // "always", "interval" (every second) or "never"
+	MaxFileBytes     int64  // 0 disables size based rotation
+	MaxFileAge       time.Duration
+	Compression      string // "none", "gzip" or "zstd"
+	MaxRetainedFiles int    // 0 keeps every segment
+}
+
+type JSONLSink struct {
+	Options  JSONLSinkOptions
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	opened   time.Time
+	dirty    bool
+	closed   bool
+	done     chan struct{}
+	wg       sync.WaitGroup // Background loop and segment compression
+	segments sync.Mutex     // Serializes compression and cleanup of segments
+}
+
+const segmentTimeFormat = "20060102T150405.000000000"
+
+// segmentPattern matches the segments of a file, compressed or not, and not
+// the files of another sink sharing the directory and a name prefix
+func segmentPattern(path string) *regexp.Regexp {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `-\d{8}T\d{6}\.\d{9}` + regexp.QuoteMeta(ext) + `(\.gz|\.zst)?$`)
+}
+
+func NewJSONLSink(options JSONLSinkOptions) (*JSONLSink, error) {
+	sink := &JSONLSink{
+		Options: options,
+		done:    make(chan struct{}),
+	}
+
+	err := os.MkdirAll(filepath.Dir(options.Path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create directory: %v", err)
+	}
+	err = sink.open()
+	if err != nil {
+		return nil, err
+	}
+
+	sink.wg.Add(1)
+	go sink.run()
+
+	return sink, nil
+}
+
+func (sink *JSONLSink) open() error {
+	file, err := os.OpenFile(sink.Options.Path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Failed to stat file: %v", err)
+	}
+	sink.file = file
+	sink.size = info.Size()
+	sink.opened = time.Now()
+	return nil
+}
+
+// run syncs the file and rotates it by age in the background
+func (sink *JSONLSink) run() {
+	defer sink.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sink.done:
+			return
+		case <-ticker.C:
+			sink.mu.Lock()
+			if sink.file == nil {
+				sink.mu.Unlock()
+				continue
+			}
+			var err error
+			if sink.Options.Fsync == "interval" && sink.dirty {
+				err = sink.sync()
+			}
+			if err == nil && sink.Options.MaxFileAge > 0 && sink.size > 0 && time.Since(sink.opened) >= sink.Options.MaxFileAge {
+				err = sink.rotate()
+			}
+			sink.mu.Unlock()
+			if err != nil {
+				log.Printf("JSONL sink %s: %v", sink.Options.Path, err)
+			}
+		}
+	}
+}
+
+func (sink *JSONLSink) sync() error {
+	err := sink.file.Sync()
+	if err != nil {
+		return fmt.Errorf("Failed to sync file: %v", err)
+	}
+	sink.dirty = false
+	return nil
+}
+
+func (sink *JSONLSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+
+	// Encode before taking the lock
+	var data []byte
+	for _, row := range rows {
+		msg, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		data = append(data, msg...)
+		data = append(data, '\n')
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if sink.closed {
+		return fmt.Errorf("File %s is closed", sink.Options.Path)
+	}
+	// Reopen a file that failed to open after a rotation
+	if sink.file == nil {
+		err := sink.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rows of a single write are never split across segments
+	if sink.Options.MaxFileBytes > 0 && sink.size > 0 && sink.size+int64(len(data)) > sink.Options.MaxFileBytes {
+		err := sink.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := sink.file.Write(data)
+	sink.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("Failed to write to file: %v", err)
+	}
+	sink.dirty = true
+
+	if sink.Options.Fsync == "always" {
+		return sink.sync()
+	}
+	return nil
+}
+
+// Flush syncs the active file to disk
+func (sink *JSONLSink) Flush(ctx context.Context) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if sink.file == nil {
+		return nil
+	}
+	return sink.sync()
+}
+
+// Health checks that the active file is open and still in place
+func (sink *JSONLSink) Health(ctx context.Context) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	if sink.file == nil {
+		return fmt.Errorf("File %s is not open", sink.Options.Path)
+	}
+	_, err := os.Stat(sink.Options.Path)
+	if err != nil {
+		return fmt.Errorf("Failed to stat file: %v", err)
+	}
+	return nil
+}
+
+// rotate closes the active file, moves it aside and opens a new one
+func (sink *JSONLSink) rotate() error {
+	err := sink.file.Sync()
+	if err != nil {
+		return fmt.Errorf("Failed to sync file: %v", err)
+	}
+	// From here on any failure leaves the file closed, and the next write
+	// opens it again
+	err = sink.file.Close()
+	sink.file = nil
+	if err != nil {
+		return fmt.Errorf("Failed to close file: %v", err)
+	}
+
+	ext := filepath.Ext(sink.Options.Path)
+	base := strings.TrimSuffix(sink.Options.Path, ext)
+	segment := fmt.Sprintf("%s-%s%s", base, time.Now().UTC().Format(segmentTimeFormat), ext)
+	err = os.Rename(sink.Options.Path, segment)
+	if err != nil {
+		return fmt.Errorf("Failed to rename segment: %v", err)
+	}
+
+	err = sink.open()
+	if err != nil {
+		return err
+	}
+	sink.dirty = false
+
+	// Compression and cleanup don't need to hold up writes
+	sink.wg.Add(1)
+	go func() {
+		defer sink.wg.Done()
+		sink.segments.Lock()
+		defer sink.segments.Unlock()
+		err := compressSegment(segment, sink.Options.Compression)
+		if err != nil {
+			log.Printf("JSONL sink %s: %v", sink.Options.Path, err)
+		}
+		err = sink.removeOldSegments()
+		if err != nil {
+			log.Printf("JSONL sink %s: %v", sink.Options.Path, err)
+		}
+	}()
+	return nil
+}
+
+// compressSegment replaces a closed segment with its compressed version
+func compressSegment(segment string, compression string) error {
+	var ext string
+	switch compression {
+	case "gzip":
+		ext = ".gz"
+	case "zstd":
+		ext = ".zst"
+	default:
+		return nil
+	}
+
+	src, err := os.Open(segment)
+	if err != nil {
+		return fmt.Errorf("Failed to open segment: %v", err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(segment+ext, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to create compressed segment: %v", err)
+	}
+	defer dst.Close()
+
+	var w io.WriteCloser
+	if compression == "gzip" {
+		w = gzip.NewWriter(dst)
+	} else {
+		w, err = zstd.NewWriter(dst)
+		if err != nil {
+			return fmt.Errorf("Failed to create zstd writer: %v", err)
+		}
+	}
+	_, err = io.Copy(w, src)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if err != nil {
+		os.Remove(segment + ext)
+		return fmt.Errorf("Failed to compress segment: %v", err)
+	}
+
+	return os.Remove(segment)
+}
+
+// removeOldSegments deletes the oldest segments beyond MaxRetainedFiles
+func (sink *JSONLSink) removeOldSegments() error {
+	if sink.Options.MaxRetainedFiles <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(sink.Options.Path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("Failed to list segments: %v", err)
+	}
+	pattern := segmentPattern(sink.Options.Path)
+	var segments []string
+	for _, entry := range entries {
+		if pattern.MatchString(entry.Name()) {
+			segments = append(segments, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	// Segment timestamps sort lexically
+	sort.Strings(segments)
+	for len(segments) > sink.Options.MaxRetainedFiles {
+		err = os.Remove(segments[0])
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Failed to remove segment: %v", err)
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
+
+func (sink *JSONLSink) Close() error {
+	sink.mu.Lock()
+	if sink.closed {
+		sink.mu.Unlock()
+		return nil
+	}
+	sink.closed = true
+	var err error
+	if sink.file != nil {
+		err = sink.file.Sync()
+		if err == nil {
+			err = sink.file.Close()
+		}
+		sink.file = nil
+	}
+	sink.mu.Unlock()
+
+	// Wait for the background loop and pending compressions
+	close(sink.done)
+	sink.wg.Wait()
+
+	if err != nil {
+		return fmt.Errorf("Failed to close file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func TestJSONLSinkRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+
+	// Files of another sink sharing the name prefix are left alone
+	others := []string{"events-orders.jsonl", "events-orders-20240101T000000.000000000.jsonl.gz"}
+	for _, name := range others {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	sink, err := NewJSONLSink(JSONLSinkOptions{
+		Path:             path,
+		Fsync:            "always",
+		MaxFileBytes:     1, // Every write after the first one rotates
+		Compression:      "gzip",
+		MaxRetainedFiles: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}})
+		if err != nil {
+			t.Fatalf("Failed to write rows: %v", err)
+		}
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	var segments []string
+	for _, entry := range entries {
+		if entry.Name() == "events.jsonl" || strings.HasPrefix(entry.Name(), "events-orders") {
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), ".jsonl.gz") {
+			t.Fatalf("Unexpected uncompressed segment %s", entry.Name())
+		}
+		segments = append(segments, entry.Name())
+	}
+	if len(segments) != 2 {
+		t.Fatalf("Expected 2 retained segments, got %v", segments)
+	}
+	for _, name := range others {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("Expected %s to survive retention: %v", name, err)
+		}
+	}
+
+	// The active file holds the last write
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read active file: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("Expected one row in the active file, got %q", data)
+	}
+}
+
+func TestJSONLSinkReopensAfterFailedOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewJSONLSink(JSONLSinkOptions{Path: path, Fsync: "never"})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	// As left by a rotation that couldn't open the new file
+	sink.mu.Lock()
+	sink.file.Close()
+	sink.file = nil
+	sink.mu.Unlock()
+
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}})
+	if err != nil {
+		t.Fatalf("Expected the write to reopen the file, got %v", err)
+	}
+	if err := sink.Health(context.Background()); err != nil {
+		t.Fatalf("Expected the reopened sink to be healthy, got %v", err)
+	}
+
+	sink.Close()
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}})
+	if err == nil {
+		t.Fatal("Expected writes to a closed sink to fail")
+	}
+}
+
+func TestJSONLSinkReopensAfterFailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewJSONLSink(JSONLSinkOptions{Path: path, Fsync: "never", MaxFileBytes: 1})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	rows := []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}}
+	err = sink.WriteRows(context.Background(), rows)
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	// The active file is gone, so the rotation can't rename it
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatalf("Failed to remove active file: %v", err)
+	}
+	err = sink.WriteRows(context.Background(), rows)
+	if err == nil || !strings.Contains(err.Error(), "Failed to rename segment") {
+		t.Fatalf("Expected the rotation to fail, got %v", err)
+	}
+
+	err = sink.WriteRows(context.Background(), rows)
+	if err != nil {
+		t.Fatalf("Expected the write to reopen the file, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read active file: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("Expected one row in the reopened file, got %q", data)
+	}
+}
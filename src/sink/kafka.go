@@ -0,0 +1,226 @@
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+	"github.com/altxtech/webhook-connector/src/utils"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "kafka",
+		Description: "Produces every event as a record to a Kafka topic.",
+		Params: []conf.Param{
+			{Name: "brokers", Type: conf.ParamString, Required: true, Description: "Comma separated host:port list."},
+			{Name: "topic", Type: conf.ParamString, Required: true},
+			{Name: "key", Type: conf.ParamString, Default: "source_id", Options: []string{"source_id", "json_path", "header", "none"}},
+			{Name: "key_path", Type: conf.ParamString, Description: "JSON path of the key in the payload, when key is json_path."},
+			{Name: "key_header", Type: conf.ParamString, Description: "Webhook request header holding the key, when key is header."},
+			{Name: "compression", Type: conf.ParamString, Default: "snappy", Options: []string{"none", "gzip", "snappy", "lz4", "zstd"}},
+			{Name: "idempotent", Type: conf.ParamBool, Default: true},
+			{Name: "tls", Type: conf.ParamBool, Default: false},
+			{Name: "username", Type: conf.ParamString, Description: "SASL/PLAIN username."},
+			{Name: "password", Type: conf.ParamString},
+		},
+		Validate: func(s conf.Sink) error {
+			if s.StringParam("key", "") == "json_path" && s.StringParam("key_path", "") == "" {
+				return errors.New("Parameter key_path is required when key is json_path.")
+			}
+			if s.StringParam("key", "") == "header" && s.StringParam("key_header", "") == "" {
+				return errors.New("Parameter key_header is required when key is header.")
+			}
+			return nil
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewKafkaSink(KafkaSinkOptions{
+			Brokers:     splitList(config.Config["brokers"].(string)),
+			Topic:       config.Config["topic"].(string),
+			Key:         config.StringParam("key", ""),
+			KeyPath:     config.StringParam("key_path", ""),
+			KeyHeader:   config.StringParam("key_header", ""),
+			Compression: config.StringParam("compression", ""),
+			Idempotent:  config.BoolParam("idempotent", false),
+			TLS:         config.BoolParam("tls", false),
+			Username:    config.StringParam("username", ""),
+			Password:    config.StringParam("password", ""),
+		})
+	})
+}
+
+// Kafka Sink
+/*
+	Produces every event as one JSON record. The record key is the source
+	id, a value taken from the payload with a JSON path, or a header of the
+	webhook request (see WithRequestHeaders), and the event metadata is sent
+	as record headers. Events without the key are produced without one.
+
+	The producer is idempotent by default, which requires acks from all
+	in-sync replicas.
+*/
+type KafkaSinkOptions struct {
+	Brokers     []string
+	Topic       string
+	Key         string // "source_id", "json_path", "header" or "none"
+	KeyPath     string // JSON path used when Key is "json_path"
+	KeyHeader   string // Request header used when Key is "header"
+	Compression string // "none", "gzip", "snappy", "lz4" or "zstd"
+	Idempotent  bool
+	TLS         bool
+	Username    string // SASL/PLAIN, when set
+	Password    string
+}
+
+type kafkaSink struct {
+	Options KafkaSinkOptions
+	client  *kgo.Client
+}
+
+func NewKafkaSink(options KafkaSinkOptions) (Sink, error) {
+
+	var sink *kafkaSink
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(options.Brokers...),
+		kgo.DefaultProduceTopic(options.Topic),
+		kgo.ProducerBatchCompression(kafkaCompression(options.Compression)),
+		kgo.ProducerLinger(0),
+	}
+	if options.Idempotent {
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	} else {
+		opts = append(opts, kgo.DisableIdempotentWrite(), kgo.RequiredAcks(kgo.LeaderAck()))
+	}
+	if options.TLS {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{}))
+	}
+	if options.Username != "" {
+		opts = append(opts, kgo.SASL(plain.Auth{User: options.Username, Pass: options.Password}.AsMechanism()))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return sink, fmt.Errorf("Error creating Kafka client: %v", err)
+	}
+
+	sink = &kafkaSink{
+		Options: options,
+		client:  client,
+	}
+	return sink, nil
+}
+
+func kafkaCompression(compression string) kgo.CompressionCodec {
+	switch compression {
+	case "gzip":
+		return kgo.GzipCompression()
+	case "snappy":
+		return kgo.SnappyCompression()
+	case "lz4":
+		return kgo.Lz4Compression()
+	case "zstd":
+		return kgo.ZstdCompression()
+	default:
+		return kgo.NoCompression()
+	}
+}
+
+func (sink *kafkaSink) record(ctx context.Context, row protoreflect.ProtoMessage) (*kgo.Record, error) {
+	value, err := encodeRow(row)
+	if err != nil {
+		return nil, err
+	}
+	record := &kgo.Record{Value: value}
+
+	event, ok := row.(*model.WebhookEvent)
+	if !ok {
+		return record, nil
+	}
+	md := event.GetMetadata()
+	record.Headers = []kgo.RecordHeader{
+		{Key: "source_id", Value: []byte(md.GetSourceId())},
+		{Key: "source_name", Value: []byte(md.GetSourceName())},
+	}
+	if md.GetReceivedAt() != nil {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: "received_at", Value: []byte(md.GetReceivedAt().AsTime().Format(time.RFC3339Nano))})
+	}
+
+	switch sink.Options.Key {
+	case "source_id":
+		record.Key = []byte(md.GetSourceId())
+	case "json_path":
+		keyValue, ok := utils.LookupJSONPath([]byte(event.GetEvent()), sink.Options.KeyPath)
+		if ok && keyValue != nil {
+			record.Key = []byte(utils.JSONValueString(keyValue))
+		}
+	case "header":
+		if key := requestHeader(ctx, sink.Options.KeyHeader); key != "" {
+			record.Key = []byte(key)
+		}
+	}
+	return record, nil
+}
+
+func (sink *kafkaSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	records := make([]*kgo.Record, len(rows))
+	for k, row := range rows {
+		record, err := sink.record(ctx, row)
+		if err != nil {
+			return err
+		}
+		records[k] = record
+	}
+
+	err := sink.client.ProduceSync(ctx, records...).FirstErr()
+	if err != nil {
+		return fmt.Errorf("Error producing records: %v", err)
+	}
+	return nil
+}
+
+func (sink *kafkaSink) Flush(ctx context.Context) error {
+	err := sink.client.Flush(ctx)
+	if err != nil {
+		return fmt.Errorf("Error flushing records: %v", err)
+	}
+	return nil
+}
+
+func (sink *kafkaSink) Health(ctx context.Context) error {
+	err := sink.client.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("Error pinging brokers: %v", err)
+	}
+	return nil
+}
+
+func (sink *kafkaSink) Close() error {
+	err := sink.client.Flush(context.Background())
+	sink.client.Close()
+	if err != nil {
+		return fmt.Errorf("Error flushing records: %v", err)
+	}
+	return nil
+}
+
+// splitList splits a comma separated parameter, dropping empty entries
+func splitList(list string) []string {
+	var result []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func TestKafkaRecordKey(t *testing.T) {
+	event := &model.WebhookEvent{
+		Metadata: &model.Metadata{SourceId: "orders", ReceivedAt: timestamppb.Now()},
+		Event:    `{"order": {"id": 9007199254740993}}`,
+	}
+	ctx := WithRequestHeaders(context.Background(), http.Header{"X-Shop-Domain": {"shop.example.com"}})
+
+	cases := []struct {
+		options KafkaSinkOptions
+		want    string
+	}{
+		{KafkaSinkOptions{Key: "source_id"}, "orders"},
+		{KafkaSinkOptions{Key: "json_path", KeyPath: "order.id"}, "9007199254740993"},
+		{KafkaSinkOptions{Key: "json_path", KeyPath: "order.missing"}, ""},
+		{KafkaSinkOptions{Key: "header", KeyHeader: "x-shop-domain"}, "shop.example.com"},
+		{KafkaSinkOptions{Key: "header", KeyHeader: "X-Missing"}, ""},
+		{KafkaSinkOptions{Key: "none"}, ""},
+	}
+	for _, c := range cases {
+		sink := &kafkaSink{Options: c.options}
+		record, err := sink.record(ctx, event)
+		if err != nil {
+			t.Fatalf("Failed to build record: %v", err)
+		}
+		if string(record.Key) != c.want {
+			t.Errorf("Expected key %q with %+v, got %q", c.want, c.options, record.Key)
+		}
+	}
+
+	// Headers only come from the write's context
+	sink := &kafkaSink{Options: KafkaSinkOptions{Key: "header", KeyHeader: "X-Shop-Domain"}}
+	record, _ := sink.record(context.Background(), event)
+	if record.Key != nil {
+		t.Errorf("Expected no key without request headers, got %q", record.Key)
+	}
+}
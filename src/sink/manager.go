@@ -0,0 +1,484 @@
+package sink
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// Sink Manager
+/*
+	Keeps one live sink per configuration id, shared by concurrent requests.
+
+	- Construction is single flight: concurrent requests for a missing sink
+	  wait for one construction instead of each building their own.
+	- At most Capacity sinks are kept. The least recently used one is closed
+	  to make room for a new one.
+	- Sinks unused for IdleTimeout are closed in the background.
+
+	Sinks are checked out with Acquire and handed back with the returned
+	release func. A sink that is evicted or removed while checked out is only
+	closed once its last user releases it, so writes never race a Close.
+	Buffered rows are flushed before a sink is closed.
+
+	Each sink remembers the Version of the configuration it was built from.
+	Acquiring it with a newer version rebuilds it, and Reconcile drops the
+	sinks of configurations deleted or changed elsewhere. Versions are
+	assigned by the database, so unlike UpdatedAt they don't depend on the
+	clocks of the instances writing the configurations.
+*/
+type SinkManagerOptions struct {
+	Capacity    int           // Maximum number of live sinks, 0 for no limit
+	IdleTimeout time.Duration // Close sinks unused for this long, 0 to keep them
+	NewSink     func(conf.Sink) (Sink, error)
+}
+
+type SinkManager struct {
+	options SinkManagerOptions
+
+	mu      sync.Mutex
+	entries map[string]*managedSink
+	lru     *list.List // Front is the most recently used
+	pending map[string]*pendingSink
+	stats   sinkCounters
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type managedSink struct {
+	id        string
+	sinkType  string
+	sink      Sink
+	version   int64 // Version of the configuration the sink was built from
+	createdAt time.Time
+	lastUsed  time.Time
+	refs      int
+	retired   bool // Removed from the manager, close when refs drops to 0
+	element   *list.Element
+}
+
+type pendingSink struct {
+	done    chan struct{}
+	err     error
+	dropped bool // Removed while under construction, don't keep it
+}
+
+type sinkCounters struct {
+	hits              int64
+	misses            int64
+	failures          int64
+	idleEvictions     int64
+	capacityEvictions int64
+}
+
+type SinkManagerStats struct {
+	Live              int         `json:"live"`
+	Capacity          int         `json:"capacity"`
+	IdleTimeout       string      `json:"idle_timeout"`
+	Hits              int64       `json:"hits"`
+	Misses            int64       `json:"misses"`
+	Failures          int64       `json:"failures"`
+	IdleEvictions     int64       `json:"idle_evictions"`
+	CapacityEvictions int64       `json:"capacity_evictions"`
+	Sinks             []SinkStats `json:"sinks"`
+}
+
+type SinkStats struct {
+	ConfigurationID string    `json:"configuration_id"`
+	Type            string    `json:"type"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastUsed        time.Time `json:"last_used"`
+	InUse           int       `json:"in_use"`
+}
+
+func NewSinkManager(options SinkManagerOptions) *SinkManager {
+	if options.NewSink == nil {
+		options.NewSink = NewSink
+	}
+	sm := &SinkManager{
+		options: options,
+		entries: map[string]*managedSink{},
+		lru:     list.New(),
+		pending: map[string]*pendingSink{},
+		done:    make(chan struct{}),
+	}
+	if options.IdleTimeout > 0 {
+		sm.wg.Add(1)
+		go sm.run()
+	}
+	return sm
+}
+
+// Acquire returns the live sink of a configuration, building it if needed.
+// The caller must call release once done with the sink.
+func (sm *SinkManager) Acquire(config *conf.Configuration) (Sink, func(), error) {
+	for {
+		sm.mu.Lock()
+		if sm.closed {
+			sm.mu.Unlock()
+			return nil, nil, fmt.Errorf("Sink manager is closed")
+		}
+
+		entry, ok := sm.entries[config.ID]
+		if ok && config.Version > entry.version {
+			// The configuration changed since the sink was built
+			closeNow := sm.detach(entry)
+			sm.mu.Unlock()
+			if closeNow {
+				sm.closeAll(context.Background(), []*managedSink{entry})
+			}
+			continue
+		}
+		if ok {
+			sm.stats.hits++
+			sm.checkout(entry)
+			sm.mu.Unlock()
+			return entry.sink, sm.releaseFunc(entry, true), nil
+		}
+
+		// Wait for a construction already in progress
+		call, ok := sm.pending[config.ID]
+		if ok {
+			sm.mu.Unlock()
+			<-call.done
+			if call.err != nil {
+				return nil, nil, call.err
+			}
+			continue // The sink may already be evicted, look it up again
+		}
+
+		call = &pendingSink{done: make(chan struct{})}
+		sm.pending[config.ID] = call
+		sm.stats.misses++
+		sm.mu.Unlock()
+
+		return sm.build(config, call)
+	}
+}
+
+func (sm *SinkManager) build(config *conf.Configuration, call *pendingSink) (Sink, func(), error) {
+	newSink, err := sm.options.NewSink(config.Sink)
+
+	sm.mu.Lock()
+	delete(sm.pending, config.ID)
+	if err != nil {
+		sm.stats.failures++
+		call.err = fmt.Errorf("Failed to create new sink: %v", err)
+		sm.mu.Unlock()
+		close(call.done)
+		return nil, nil, call.err
+	}
+
+	now := time.Now()
+	entry := &managedSink{
+		id:        config.ID,
+		sinkType:  config.Sink.Type,
+		sink:      newSink,
+		version:   config.Version,
+		createdAt: now,
+		lastUsed:  now,
+	}
+	sm.checkout(entry)
+
+	// A sink removed while it was built only serves this request
+	var evicted []*managedSink
+	if call.dropped || sm.closed {
+		entry.retired = true
+	} else {
+		entry.element = sm.lru.PushFront(entry)
+		sm.entries[config.ID] = entry
+		evicted = sm.evictOverCapacity()
+	}
+	sm.mu.Unlock()
+	close(call.done)
+
+	sm.closeAll(context.Background(), evicted)
+	return newSink, sm.releaseFunc(entry, true), nil
+}
+
+// checkout marks the entry as used. Must hold the lock.
+func (sm *SinkManager) checkout(entry *managedSink) {
+	entry.refs++
+	entry.lastUsed = time.Now()
+	if entry.element != nil {
+		sm.lru.MoveToFront(entry.element)
+	}
+}
+
+// releaseFunc hands the entry back. Only touched releases count as use, so
+// health checks don't keep idle sinks alive.
+func (sm *SinkManager) releaseFunc(entry *managedSink, touch bool) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sm.mu.Lock()
+			entry.refs--
+			if touch {
+				entry.lastUsed = time.Now()
+			}
+			closeNow := entry.retired && entry.refs == 0
+			sm.mu.Unlock()
+
+			if closeNow {
+				sm.closeAll(context.Background(), []*managedSink{entry})
+			}
+		})
+	}
+}
+
+// detach removes the entry from the manager and returns whether it can be
+// closed right away. Must hold the lock.
+func (sm *SinkManager) detach(entry *managedSink) bool {
+	delete(sm.entries, entry.id)
+	if entry.element != nil {
+		sm.lru.Remove(entry.element)
+		entry.element = nil
+	}
+	entry.retired = true
+	return entry.refs == 0
+}
+
+// evictOverCapacity detaches least recently used sinks until the manager is
+// within capacity. Must hold the lock.
+func (sm *SinkManager) evictOverCapacity() []*managedSink {
+	var evicted []*managedSink
+	for sm.options.Capacity > 0 && sm.lru.Len() > sm.options.Capacity {
+		entry := sm.lru.Back().Value.(*managedSink)
+		sm.stats.capacityEvictions++
+		if sm.detach(entry) {
+			evicted = append(evicted, entry)
+		}
+	}
+	return evicted
+}
+
+// closeAll flushes and closes the sinks of detached entries
+func (sm *SinkManager) closeAll(ctx context.Context, entries []*managedSink) error {
+	var firstErr error
+	for _, entry := range entries {
+		err := closeSink(ctx, entry.sink)
+		if err != nil {
+			log.Printf("Failed to close sink for configuration %s: %v", entry.id, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("Error closing sink for configuration %s: %v", entry.id, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+func (sm *SinkManager) run() {
+	defer sm.wg.Done()
+	interval := sm.options.IdleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.done:
+			return
+		case <-ticker.C:
+			sm.evictIdle()
+		}
+	}
+}
+
+// evictIdle closes the sinks that were not used for IdleTimeout
+func (sm *SinkManager) evictIdle() {
+	sm.mu.Lock()
+	var evicted []*managedSink
+	cutoff := time.Now().Add(-sm.options.IdleTimeout)
+	for element := sm.lru.Back(); element != nil; {
+		entry := element.Value.(*managedSink)
+		element = element.Prev()
+		if entry.refs > 0 || entry.lastUsed.After(cutoff) {
+			continue
+		}
+		sm.stats.idleEvictions++
+		sm.detach(entry)
+		evicted = append(evicted, entry)
+	}
+	sm.mu.Unlock()
+
+	sm.closeAll(context.Background(), evicted)
+}
+
+// Remove closes the sink of a configuration, if there is one. A sink being
+// built is discarded once ready; one in use is closed when released.
+func (sm *SinkManager) Remove(ctx context.Context, id string) error {
+	sm.mu.Lock()
+	if call, ok := sm.pending[id]; ok {
+		call.dropped = true
+	}
+	entry, ok := sm.entries[id]
+	if !ok {
+		sm.mu.Unlock()
+		return nil
+	}
+	closeNow := sm.detach(entry)
+	sm.mu.Unlock()
+
+	if !closeNow {
+		return nil
+	}
+	err := closeSink(ctx, entry.sink)
+	if err != nil {
+		return fmt.Errorf("Error terminating sink: %v", err)
+	}
+	return nil
+}
+
+// Refresh drops the sink of a configuration if it was built from an older
+// version of it. The sink is rebuilt on its next use.
+func (sm *SinkManager) Refresh(ctx context.Context, config conf.Configuration) error {
+	sm.mu.Lock()
+	entry, ok := sm.entries[config.ID]
+	if !ok || config.Version <= entry.version {
+		sm.mu.Unlock()
+		return nil
+	}
+	closeNow := sm.detach(entry)
+	sm.mu.Unlock()
+
+	if !closeNow {
+		return nil
+	}
+	return sm.closeAll(ctx, []*managedSink{entry})
+}
+
+// Reconcile drops the sinks whose configuration is not in configs anymore,
+// or was updated since the sink was built
+func (sm *SinkManager) Reconcile(ctx context.Context, configs []conf.Configuration) error {
+	current := make(map[string]int64, len(configs))
+	for _, config := range configs {
+		current[config.ID] = config.Version
+	}
+
+	sm.mu.Lock()
+	var stale []*managedSink
+	for id, entry := range sm.entries {
+		version, ok := current[id]
+		if ok && version <= entry.version {
+			continue
+		}
+		if sm.detach(entry) {
+			stale = append(stale, entry)
+		}
+	}
+	sm.mu.Unlock()
+
+	return sm.closeAll(ctx, stale)
+}
+
+// closeSink flushes buffered rows before closing, so a failed flush is
+// reported with the request's deadline instead of at Close
+func closeSink(ctx context.Context, s Sink) error {
+	flushErr := s.Flush(ctx)
+	err := s.Close()
+	if flushErr != nil {
+		return fmt.Errorf("Error flushing sink: %v", flushErr)
+	}
+	return err
+}
+
+// Health checks every live sink and returns the errors by configuration id
+func (sm *SinkManager) Health(ctx context.Context) map[string]string {
+	entries, release := sm.acquireAll()
+	defer release()
+
+	failures := map[string]string{}
+	for _, entry := range entries {
+		err := entry.sink.Health(ctx)
+		if err != nil {
+			failures[entry.id] = err.Error()
+		}
+	}
+	return failures
+}
+
+// acquireAll checks out every live sink
+func (sm *SinkManager) acquireAll() ([]*managedSink, func()) {
+	sm.mu.Lock()
+	entries := make([]*managedSink, 0, len(sm.entries))
+	releases := make([]func(), 0, len(sm.entries))
+	for _, entry := range sm.entries {
+		entry.refs++
+		entries = append(entries, entry)
+		releases = append(releases, sm.releaseFunc(entry, false))
+	}
+	sm.mu.Unlock()
+
+	return entries, func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+}
+
+func (sm *SinkManager) Stats() SinkManagerStats {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	stats := SinkManagerStats{
+		Live:              len(sm.entries),
+		Capacity:          sm.options.Capacity,
+		IdleTimeout:       sm.options.IdleTimeout.String(),
+		Hits:              sm.stats.hits,
+		Misses:            sm.stats.misses,
+		Failures:          sm.stats.failures,
+		IdleEvictions:     sm.stats.idleEvictions,
+		CapacityEvictions: sm.stats.capacityEvictions,
+		Sinks:             make([]SinkStats, 0, len(sm.entries)),
+	}
+	for _, entry := range sm.entries {
+		stats.Sinks = append(stats.Sinks, SinkStats{
+			ConfigurationID: entry.id,
+			Type:            entry.sinkType,
+			CreatedAt:       entry.createdAt,
+			LastUsed:        entry.lastUsed,
+			InUse:           entry.refs,
+		})
+	}
+	sort.Slice(stats.Sinks, func(i, j int) bool {
+		return stats.Sinks[i].LastUsed.After(stats.Sinks[j].LastUsed)
+	})
+	return stats
+}
+
+// Close stops eviction and closes every live sink. Sinks still in use are
+// closed when released.
+func (sm *SinkManager) Close() error {
+	return sm.Shutdown(context.Background())
+}
+
+// Shutdown is Close with a deadline for flushing buffered rows
+func (sm *SinkManager) Shutdown(ctx context.Context) error {
+	sm.mu.Lock()
+	if sm.closed {
+		sm.mu.Unlock()
+		return nil
+	}
+	sm.closed = true
+	var closable []*managedSink
+	for _, entry := range sm.entries {
+		if sm.detach(entry) {
+			closable = append(closable, entry)
+		}
+	}
+	sm.mu.Unlock()
+
+	close(sm.done)
+	sm.wg.Wait()
+	return sm.closeAll(ctx, closable)
+}
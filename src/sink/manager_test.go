@@ -0,0 +1,197 @@
+package sink
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+type fakeSink struct {
+	closed atomic.Int32
+}
+
+func (s *fakeSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	return nil
+}
+func (s *fakeSink) Flush(ctx context.Context) error  { return nil }
+func (s *fakeSink) Health(ctx context.Context) error { return nil }
+func (s *fakeSink) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+// fakeSinks builds fake sinks and remembers them
+type fakeSinks struct {
+	mu    sync.Mutex
+	built []*fakeSink
+	delay time.Duration
+}
+
+func (f *fakeSinks) new(config conf.Sink) (Sink, error) {
+	time.Sleep(f.delay)
+	s := &fakeSink{}
+	f.mu.Lock()
+	f.built = append(f.built, s)
+	f.mu.Unlock()
+	return s, nil
+}
+
+func testConfig(id string) *conf.Configuration {
+	return &conf.Configuration{ID: id, Sink: conf.Sink{Type: "fake"}}
+}
+
+func TestSinkManagerSingleFlight(t *testing.T) {
+	fakes := &fakeSinks{delay: 50 * time.Millisecond}
+	sm := NewSinkManager(SinkManagerOptions{NewSink: fakes.new})
+	defer sm.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, release, err := sm.Acquire(testConfig("a"))
+			if err != nil {
+				t.Errorf("Failed to acquire sink: %v", err)
+				return
+			}
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if len(fakes.built) != 1 {
+		t.Fatalf("Expected a single construction, got %d", len(fakes.built))
+	}
+	stats := sm.Stats()
+	if stats.Misses != 1 || stats.Live != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSinkManagerCapacity(t *testing.T) {
+	fakes := &fakeSinks{}
+	sm := NewSinkManager(SinkManagerOptions{Capacity: 2, NewSink: fakes.new})
+	defer sm.Close()
+
+	for _, id := range []string{"a", "b"} {
+		_, release, _ := sm.Acquire(testConfig(id))
+		release()
+	}
+	// Using a makes b the least recently used
+	_, release, _ := sm.Acquire(testConfig("a"))
+	release()
+
+	// c is checked out while b is evicted
+	_, releaseC, _ := sm.Acquire(testConfig("c"))
+	if fakes.built[1].closed.Load() != 1 {
+		t.Fatalf("Expected the least recently used sink to be closed")
+	}
+	if fakes.built[0].closed.Load() != 0 {
+		t.Fatalf("Expected the recently used sink to stay open")
+	}
+
+	// A checked out sink is only closed once released
+	sm.Remove(context.Background(), "c")
+	if fakes.built[2].closed.Load() != 0 {
+		t.Fatalf("Sink closed while in use")
+	}
+	releaseC()
+	if fakes.built[2].closed.Load() != 1 {
+		t.Fatalf("Expected the removed sink to be closed on release")
+	}
+	if stats := sm.Stats(); stats.Live != 1 || stats.CapacityEvictions != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSinkManagerIdleEviction(t *testing.T) {
+	fakes := &fakeSinks{}
+	sm := NewSinkManager(SinkManagerOptions{IdleTimeout: time.Hour, NewSink: fakes.new})
+	defer sm.Close()
+
+	_, release, _ := sm.Acquire(testConfig("a"))
+	release()
+	_, releaseB, _ := sm.Acquire(testConfig("b"))
+	defer releaseB()
+
+	// Pretend both were last used long ago
+	sm.mu.Lock()
+	for _, entry := range sm.entries {
+		entry.lastUsed = time.Now().Add(-2 * time.Hour)
+	}
+	sm.mu.Unlock()
+	sm.evictIdle()
+
+	if fakes.built[0].closed.Load() != 1 {
+		t.Fatalf("Expected the idle sink to be closed")
+	}
+	if fakes.built[1].closed.Load() != 0 {
+		t.Fatalf("Sink closed while in use")
+	}
+	if stats := sm.Stats(); stats.Live != 1 || stats.IdleEvictions != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSinkManagerFollowsConfigurationChanges(t *testing.T) {
+	fakes := &fakeSinks{}
+	sm := NewSinkManager(SinkManagerOptions{NewSink: fakes.new})
+	defer sm.Close()
+
+	config := testConfig("a")
+	config.Version = 2
+	config.UpdatedAt = time.Now()
+	_, release, _ := sm.Acquire(config)
+	release()
+
+	// A request holding an older copy keeps using the current sink
+	older := *config
+	older.Version = 1
+	_, release, _ = sm.Acquire(&older)
+	release()
+	// So does the same version seen through a skewed clock
+	skewed := *config
+	skewed.UpdatedAt = config.UpdatedAt.Add(time.Hour)
+	_, release, _ = sm.Acquire(&skewed)
+	release()
+	if len(fakes.built) != 1 {
+		t.Fatalf("Expected the sink to be reused, got %d constructions", len(fakes.built))
+	}
+
+	// A newer version rebuilds it, even if its clock lags
+	newer := *config
+	newer.Version = 3
+	newer.UpdatedAt = config.UpdatedAt.Add(-time.Minute)
+	_, release, _ = sm.Acquire(&newer)
+	release()
+	if len(fakes.built) != 2 || fakes.built[0].closed.Load() != 1 {
+		t.Fatalf("Expected the outdated sink to be closed and rebuilt")
+	}
+
+	// Pushed changes drop the sink only if it is outdated
+	sm.Refresh(context.Background(), older)
+	if fakes.built[1].closed.Load() != 0 {
+		t.Fatalf("Sink closed by an older configuration")
+	}
+
+	// Sinks of configurations deleted elsewhere are dropped
+	_, release, _ = sm.Acquire(testConfig("b"))
+	release()
+	err := sm.Reconcile(context.Background(), []conf.Configuration{newer})
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if fakes.built[2].closed.Load() != 1 || fakes.built[1].closed.Load() != 0 {
+		t.Fatalf("Expected only the sink of the deleted configuration to be closed")
+	}
+	if stats := sm.Stats(); stats.Live != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+}
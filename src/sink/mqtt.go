@@ -0,0 +1,143 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "mqtt",
+		Description: "Publishes every event to an MQTT topic.",
+		Params: []conf.Param{
+			{Name: "broker", Type: conf.ParamString, Required: true, Description: "e.g. tcp://localhost:1883"},
+			{Name: "topic", Type: conf.ParamString, Required: true, Description: "Template."},
+			{Name: "qos", Type: conf.ParamInteger, Default: 1},
+			{Name: "retained", Type: conf.ParamBool, Default: false},
+			{Name: "client_id", Type: conf.ParamString, Description: "Generated when not set."},
+			{Name: "username", Type: conf.ParamString},
+			{Name: "password", Type: conf.ParamString},
+		},
+		Validate: func(s conf.Sink) error {
+			if qos := s.IntParam("qos", 1); qos < 0 || qos > 2 {
+				return errors.New("Parameter qos must be 0, 1 or 2.")
+			}
+			return nil
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewMQTTSink(
+			config.Config["broker"].(string),
+			config.Config["topic"].(string),
+			config.IntParam("qos", 0),
+			config.BoolParam("retained", false),
+			config.StringParam("client_id", ""),
+			config.StringParam("username", ""),
+			config.StringParam("password", ""),
+		)
+	})
+}
+
+// MQTT Sink
+/*
+	Publishes every event as JSON to a topic built from a template (see
+	template.go), e.g. "webhooks/{source_id}". With QoS 1 (the default) a
+	write only succeeds once the broker has acknowledged every message.
+*/
+type mqttSink struct {
+	Topic    string
+	QoS      byte
+	Retained bool
+	client   mqtt.Client
+}
+
+const mqttTimeout = 30 * time.Second
+
+func NewMQTTSink(broker string, topic string, qos int, retained bool, clientID string, username string, password string) (Sink, error) {
+
+	var sink *mqttSink
+
+	if clientID == "" {
+		clientID = "webhook-connector-" + uuid.NewString()
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetAutoReconnect(true)
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttTimeout) {
+		return sink, fmt.Errorf("Timed out connecting to MQTT broker")
+	}
+	if token.Error() != nil {
+		return sink, fmt.Errorf("Error connecting to MQTT broker: %v", token.Error())
+	}
+
+	sink = &mqttSink{
+		Topic:    topic,
+		QoS:      byte(qos),
+		Retained: retained,
+		client:   client,
+	}
+	return sink, nil
+}
+
+// mqttWait blocks until the token completes, the context is done or mqttTimeout passes
+func mqttWait(ctx context.Context, token mqtt.Token) error {
+	timer := time.NewTimer(mqttTimeout)
+	defer timer.Stop()
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("Timed out after %s", mqttTimeout)
+	}
+}
+
+func (sink *mqttSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	tokens := make([]mqtt.Token, len(rows))
+	for k, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		tokens[k] = sink.client.Publish(renderTemplate(sink.Topic, rowPlaceholders(row)), sink.QoS, sink.Retained, encoded)
+	}
+
+	for _, token := range tokens {
+		err := mqttWait(ctx, token)
+		if err != nil {
+			return fmt.Errorf("Error publishing message: %v", err)
+		}
+	}
+	return nil
+}
+
+func (sink *mqttSink) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (sink *mqttSink) Health(ctx context.Context) error {
+	if !sink.client.IsConnectionOpen() {
+		return fmt.Errorf("Not connected to MQTT broker")
+	}
+	return nil
+}
+
+func (sink *mqttSink) Close() error {
+	// Give in-flight messages up to a second to complete
+	sink.client.Disconnect(1000)
+	return nil
+}
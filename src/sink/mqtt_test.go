@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func TestMQTTSinkValidation(t *testing.T) {
+	for _, qos := range []float64{-1, 3} {
+		sink := conf.Sink{Type: "mqtt", Config: map[string]interface{}{"broker": "tcp://localhost:1883", "topic": "t", "qos": qos}}
+		if sink.Validate() == nil {
+			t.Errorf("Expected qos %v to be rejected", qos)
+		}
+	}
+}
+
+// Runs against an MQTT broker, e.g.
+// docker run -p 1883:1883 eclipse-mosquitto:1.6
+// MQTT_BROKER=tcp://localhost:1883 go test ./sink
+func TestMQTTSink(t *testing.T) {
+	broker := os.Getenv("MQTT_BROKER")
+	if broker == "" {
+		t.Skip("MQTT_BROKER not set")
+	}
+	source := "webhook-connector-test-" + time.Now().Format("20060102150405")
+
+	subscriber := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker).SetClientID(source))
+	if token := subscriber.Connect(); token.Wait() && token.Error() != nil {
+		t.Fatalf("Failed to connect: %v", token.Error())
+	}
+	defer subscriber.Disconnect(0)
+	received := make(chan mqtt.Message, 1)
+	token := subscriber.Subscribe("webhooks/"+source, 1, func(_ mqtt.Client, message mqtt.Message) {
+		received <- message
+	})
+	if token.Wait() && token.Error() != nil {
+		t.Fatalf("Failed to subscribe: %v", token.Error())
+	}
+
+	sink, err := NewMQTTSink(broker, "webhooks/{source_id}", 1, false, "", "", "")
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Health(context.Background()); err != nil {
+		t.Fatalf("Expected a healthy sink, got %v", err)
+	}
+	event := &model.WebhookEvent{
+		Metadata: &model.Metadata{SourceId: source, ReceivedAt: timestamppb.Now()},
+		Event:    `{"id": 1}`,
+	}
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{event})
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	select {
+	case message := <-received:
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(message.Payload(), &decoded); err != nil {
+			t.Fatalf("Expected a JSON payload, got %s", message.Payload())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("No message received")
+	}
+}
@@ -0,0 +1,114 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "nats",
+		Description: "Publishes every event to a NATS JetStream subject.",
+		Params: []conf.Param{
+			{Name: "url", Type: conf.ParamString, Required: true},
+			{Name: "subject", Type: conf.ParamString, Required: true, Description: "Template."},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewNATSSink(config.Config["url"].(string), config.Config["subject"].(string))
+	})
+}
+
+// NATS JetStream Sink
+/*
+	Publishes every event as JSON to a subject, waiting for the JetStream
+	ack. The subject is a template (see template.go) and must be bound to an
+	existing stream.
+
+	Each message carries a Nats-Msg-Id derived from the event's source and
+	payload (see eventID), so retried publishes and redeliveries are dropped
+	by the stream's duplicate window. Metadata is sent
+	as message headers.
+*/
+type natsSink struct {
+	Subject string
+	conn    *nats.Conn
+	js      jetstream.JetStream
+}
+
+func NewNATSSink(url string, subject string) (Sink, error) {
+
+	var sink *natsSink
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return sink, fmt.Errorf("Error connecting to NATS: %v", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return sink, fmt.Errorf("Error creating JetStream context: %v", err)
+	}
+
+	sink = &natsSink{
+		Subject: subject,
+		conn:    conn,
+		js:      js,
+	}
+	return sink, nil
+}
+
+func (sink *natsSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	for _, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		id, err := eventID(row)
+		if err != nil {
+			return err
+		}
+		msg := nats.NewMsg(renderTemplate(sink.Subject, rowPlaceholders(row)))
+		msg.Data = encoded
+		for key, value := range rowAttributes(row) {
+			msg.Header.Set(key, value)
+		}
+		msg.Header.Set(jetstream.MsgIDHeader, id)
+
+		_, err = sink.js.PublishMsg(ctx, msg)
+		if err != nil {
+			return fmt.Errorf("Error publishing message: %v", err)
+		}
+	}
+	return nil
+}
+
+func (sink *natsSink) Flush(ctx context.Context) error {
+	err := sink.conn.FlushWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("Error flushing connection: %v", err)
+	}
+	return nil
+}
+
+func (sink *natsSink) Health(ctx context.Context) error {
+	_, err := sink.js.AccountInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("Error getting JetStream account info: %v", err)
+	}
+	return nil
+}
+
+func (sink *natsSink) Close() error {
+	// Drain waits for pending publishes before closing the connection
+	err := sink.conn.Drain()
+	if err != nil {
+		return fmt.Errorf("Error draining connection: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+// fakeJetStream records published messages, failing them with err when set
+type fakeJetStream struct {
+	jetstream.JetStream
+	msgs []*nats.Msg
+	err  error
+}
+
+func (js *fakeJetStream) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	js.msgs = append(js.msgs, msg)
+	if js.err != nil {
+		return nil, js.err
+	}
+	return &jetstream.PubAck{Stream: "EVENTS", Sequence: uint64(len(js.msgs))}, nil
+}
+
+func TestNATSSinkMessages(t *testing.T) {
+	js := &fakeJetStream{}
+	sink := &natsSink{Subject: "events.{source_id}", js: js}
+
+	received := timestamppb.New(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC))
+	event := func(source string, payload string, loaded time.Time) *model.WebhookEvent {
+		return &model.WebhookEvent{
+			Metadata: &model.Metadata{SourceId: source, ReceivedAt: received, LoadedAt: timestamppb.New(loaded)},
+			Event:    payload,
+		}
+	}
+	rows := []protoreflect.ProtoMessage{
+		event("a", "{\"n\":1}", time.Now()),
+		event("a", "{\"n\":1}", time.Now().Add(time.Minute)), // Delivered again
+		event("a", "{\"n\":2}", time.Now()),
+		event("b", "{\"n\":1}", time.Now()),
+	}
+	if err := sink.WriteRows(context.Background(), rows); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if len(js.msgs) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(js.msgs))
+	}
+
+	first := js.msgs[0]
+	if first.Subject != "events.a" || js.msgs[3].Subject != "events.b" {
+		t.Fatalf("Expected subjects per source, got %s and %s", first.Subject, js.msgs[3].Subject)
+	}
+	if first.Header.Get("source_id") != "a" || first.Header.Get("received_at") != "2024-02-03T04:05:06Z" {
+		t.Fatalf("Expected the metadata headers, got %v", first.Header)
+	}
+	if !strings.Contains(string(first.Data), "\"event\":\"{\\\"n\\\":1}\"") {
+		t.Fatalf("Expected the event as JSON, got %s", first.Data)
+	}
+
+	// Only the source and payload make the message id
+	ids := make([]string, len(js.msgs))
+	for i, msg := range js.msgs {
+		ids[i] = msg.Header.Get(jetstream.MsgIDHeader)
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Fatalf("Expected a redelivered event to keep its message id, got %q and %q", ids[0], ids[1])
+	}
+	if ids[0] == ids[2] || ids[0] == ids[3] {
+		t.Fatalf("Expected different events to get different message ids, got %v", ids)
+	}
+}
+
+func TestNATSSinkErrors(t *testing.T) {
+	_, err := NewNATSSink("nats://127.0.0.1:1", "events")
+	if err == nil || !strings.Contains(err.Error(), "Error connecting to NATS") {
+		t.Fatalf("Expected a connection error, got %v", err)
+	}
+
+	// The first failed publish stops the write
+	js := &fakeJetStream{err: errors.New("nats: no response from stream")}
+	sink := &natsSink{Subject: "events", js: js}
+	rows := []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}, &model.WebhookEvent{Event: "[]"}}
+	err = sink.WriteRows(context.Background(), rows)
+	if err == nil || !strings.Contains(err.Error(), "Error publishing message") {
+		t.Fatalf("Expected the failed publish to be reported, got %v", err)
+	}
+	if len(js.msgs) != 1 {
+		t.Fatalf("Expected 1 publish attempt, got %d", len(js.msgs))
+	}
+}
+
+// Runs against a NATS server with JetStream, e.g.
+// docker run -p 4222:4222 nats:2 -js
+// NATS_URL=nats://localhost:4222 go test ./sink
+func TestNATSSink(t *testing.T) {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		t.Skip("NATS_URL not set")
+	}
+	ctx := context.Background()
+	name := "WEBHOOK_CONNECTOR_TEST_" + time.Now().Format("20060102150405")
+
+	conn, err := nats.Connect(url)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatalf("Failed to create JetStream context: %v", err)
+	}
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: name, Subjects: []string{name + ".>"}})
+	if err != nil {
+		t.Fatalf("Failed to create stream: %v", err)
+	}
+	defer js.DeleteStream(ctx, name)
+
+	sink, err := NewNATSSink(url, name+".{source_id}")
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Health(ctx); err != nil {
+		t.Fatalf("Expected the sink to be healthy, got %v", err)
+	}
+
+	// The second delivery of the event falls in the duplicate window
+	for i := 0; i < 2; i++ {
+		event := &model.WebhookEvent{
+			Metadata: &model.Metadata{SourceId: "abc", LoadedAt: timestamppb.Now()},
+			Event:    "{}",
+		}
+		if err := sink.WriteRows(ctx, []protoreflect.ProtoMessage{event}); err != nil {
+			t.Fatalf("Failed to write rows: %v", err)
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get stream info: %v", err)
+	}
+	if info.State.Msgs != 1 {
+		t.Fatalf("Expected 1 message, got %d", info.State.Msgs)
+	}
+	msg, err := stream.GetLastMsgForSubject(ctx, name+".abc")
+	if err != nil {
+		t.Fatalf("Failed to get message: %v", err)
+	}
+	if msg.Header.Get("source_id") != "abc" {
+		t.Fatalf("Expected the source_id header, got %v", msg.Header)
+	}
+}
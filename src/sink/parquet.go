@@ -0,0 +1,211 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	parquetcompress "github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/compress/gzip"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "parquet",
+		Description: "Writes rolling parquet files of typed columns into a local directory, optionally uploading them to GCS or S3.",
+		Params: append(append(fileSinkParams(300),
+			conf.Param{Name: "compression", Type: conf.ParamString, Default: "snappy", Options: []string{"none", "snappy", "gzip", "zstd"}},
+			conf.Param{Name: "row_group_rows", Type: conf.ParamInteger, Default: 10000},
+		), uploadParams()...),
+		Validate: validateUploadTo,
+	}, func(config conf.Sink) (Sink, error) {
+		options := fileSinkOptions(config)
+		options.BlockRows = config.IntParam("row_group_rows", 0)
+		return NewParquetSink(options)
+	})
+}
+
+// Parquet file Sink
+/*
+	Writes rolling parquet files of typed columns (see columns.go) into a
+	directory. Every column is optional, timestamps are stored as
+	TIMESTAMP(MICROS) so Spark and BigQuery read them as timestamps.
+
+	With upload_to set, completed files are uploaded to GCS or S3 under the
+	given prefix and then removed from the directory (see rolling.go).
+*/
+type FileSinkOptions struct {
+	Directory    string
+	FilePrefix   string
+	Columns      []conf.Column
+	Compression  string
+	MaxFileBytes int64
+	MaxFileAge   time.Duration
+	BlockRows    int       // Rows per parquet row group / avro block
+	UploadTo     string    // gs://bucket/prefix or s3://bucket/prefix, "" to keep files local
+	S3           S3Options // Used when UploadTo is an s3:// URL
+}
+
+type parquetSink struct {
+	Options FileSinkOptions
+	schema  *parquet.Schema
+	order   []int // Column index in the schema -> index in Options.Columns
+	files   *rollingFile
+}
+
+func NewParquetSink(options FileSinkOptions) (Sink, error) {
+
+	var sink *parquetSink
+
+	group := parquet.Group{}
+	for _, column := range options.Columns {
+		group[column.Name] = parquet.Optional(parquetNode(column.Type))
+	}
+	schema := parquet.NewSchema("webhook_event", group)
+
+	// Groups order their columns by name
+	positions := map[string]int{}
+	for k, column := range options.Columns {
+		positions[column.Name] = k
+	}
+	order := []int{}
+	for _, path := range schema.Columns() {
+		order = append(order, positions[path[0]])
+	}
+
+	var codec parquetcompress.Codec
+	switch options.Compression {
+	case "none":
+		codec = &parquet.Uncompressed
+	case "gzip":
+		codec = &gzip.Codec{}
+	case "zstd":
+		codec = &zstd.Codec{}
+	default:
+		codec = &snappy.Codec{}
+	}
+
+	sink = &parquetSink{
+		Options: options,
+		schema:  schema,
+		order:   order,
+	}
+	files, err := newRollingFile(options.Directory, options.FilePrefix, ".parquet", options.MaxFileBytes, options.MaxFileAge, func(w io.Writer) (rowEncoder, error) {
+		return &parquetEncoder{
+			sink:      sink,
+			writer:    parquet.NewWriter(w, schema, parquet.Compression(codec)),
+			blockRows: options.BlockRows,
+		}, nil
+	})
+	if err != nil {
+		return sink, err
+	}
+	sink.files = files
+	err = uploadCompletedFiles(files, options, "application/vnd.apache.parquet")
+	if err != nil {
+		files.Close()
+		return sink, err
+	}
+	return sink, nil
+}
+
+func parquetNode(columnType string) parquet.Node {
+	switch columnType {
+	case "int64":
+		return parquet.Int(64)
+	case "float64":
+		return parquet.Leaf(parquet.DoubleType)
+	case "bool":
+		return parquet.Leaf(parquet.BooleanType)
+	case "timestamp":
+		return parquet.Timestamp(parquet.Microsecond)
+	default:
+		return parquet.String()
+	}
+}
+
+func parquetValue(value interface{}) parquet.Value {
+	switch v := value.(type) {
+	case string:
+		return parquet.ByteArrayValue([]byte(v))
+	case int64:
+		return parquet.Int64Value(v)
+	case float64:
+		return parquet.DoubleValue(v)
+	case bool:
+		return parquet.BooleanValue(v)
+	case time.Time:
+		return parquet.Int64Value(v.UnixMicro())
+	default:
+		return parquet.NullValue()
+	}
+}
+
+type parquetEncoder struct {
+	sink      *parquetSink
+	writer    *parquet.Writer
+	blockRows int
+	buffered  int
+}
+
+func (e *parquetEncoder) Write(values []interface{}) error {
+	row := make(parquet.Row, len(e.sink.order))
+	for columnIndex, k := range e.sink.order {
+		definitionLevel := 1
+		if values[k] == nil {
+			definitionLevel = 0
+		}
+		row[columnIndex] = parquetValue(values[k]).Level(0, definitionLevel, columnIndex)
+	}
+	_, err := e.writer.WriteRows([]parquet.Row{row})
+	if err != nil {
+		return err
+	}
+
+	// Cut a row group every blockRows rows
+	e.buffered++
+	if e.blockRows > 0 && e.buffered >= e.blockRows {
+		e.buffered = 0
+		return e.writer.Flush()
+	}
+	return nil
+}
+
+func (e *parquetEncoder) Close() error {
+	return e.writer.Close()
+}
+
+func (sink *parquetSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	values := make([][]interface{}, len(rows))
+	for k, row := range rows {
+		values[k] = extractColumns(row, sink.Options.Columns)
+	}
+	return sink.files.Write(values)
+}
+
+func (sink *parquetSink) Flush(ctx context.Context) error {
+	err := sink.files.Flush()
+	if err != nil {
+		return fmt.Errorf("Error flushing parquet file: %v", err)
+	}
+	return nil
+}
+
+func (sink *parquetSink) Health(ctx context.Context) error {
+	return sink.files.Health(ctx)
+}
+
+func (sink *parquetSink) Close() error {
+	err := sink.files.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing parquet file: %v", err)
+	}
+	return nil
+}
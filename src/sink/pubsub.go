@@ -0,0 +1,160 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "pubsub",
+		Description: "Publishes every event to a Pub/Sub topic.",
+		Params: []conf.Param{
+			{Name: "project", Type: conf.ParamString, Required: true},
+			{Name: "topic", Type: conf.ParamString, Required: true},
+			{Name: "format", Type: conf.ParamString, Default: "json", Options: []string{"json", "proto"}},
+			{Name: "ordering", Type: conf.ParamBool, Default: true, Description: "Use the source id as ordering key."},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewPubSubSink(
+			config.Config["project"].(string),
+			config.Config["topic"].(string),
+			config.StringParam("format", ""),
+			config.BoolParam("ordering", false),
+		)
+	})
+}
+
+// Pub/Sub Sink
+/*
+	Publishes every event as one message. The payload is the WebhookEvent
+	encoded as JSON or binary proto, and its metadata is copied into the
+	message attributes so subscribers can filter without decoding.
+
+	With ordering enabled, events of the same source share an ordering key.
+	The subscription must have message ordering enabled for it to matter.
+
+	Set PUBSUB_EMULATOR_HOST to run against the Pub/Sub emulator.
+*/
+type pubSubSink struct {
+	Project  string
+	Topic    string
+	Format   string // "json" or "proto"
+	Ordering bool
+	client   *pubsub.Client
+	topic    *pubsub.Topic
+}
+
+func NewPubSubSink(project string, topic string, format string, ordering bool) (Sink, error) {
+
+	var sink *pubSubSink
+
+	client, err := pubsub.NewClient(context.Background(), project)
+	if err != nil {
+		return sink, fmt.Errorf("Error creating Pub/Sub client: %v", err)
+	}
+
+	t := client.Topic(topic)
+	t.EnableMessageOrdering = ordering
+
+	sink = &pubSubSink{
+		Project:  project,
+		Topic:    topic,
+		Format:   format,
+		Ordering: ordering,
+		client:   client,
+		topic:    t,
+	}
+	return sink, nil
+}
+
+func (sink *pubSubSink) message(row protoreflect.ProtoMessage) (*pubsub.Message, error) {
+	var data []byte
+	var err error
+	switch sink.Format {
+	case "proto":
+		data, err = proto.Marshal(row)
+	default:
+		data, err = encodeRow(row)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling row: %v", err)
+	}
+
+	msg := &pubsub.Message{
+		Data:       data,
+		Attributes: map[string]string{"format": sink.Format},
+	}
+	if event, ok := row.(*model.WebhookEvent); ok {
+		md := event.GetMetadata()
+		msg.Attributes["source_id"] = md.GetSourceId()
+		msg.Attributes["source_name"] = md.GetSourceName()
+		if md.GetReceivedAt() != nil {
+			msg.Attributes["received_at"] = md.GetReceivedAt().AsTime().Format(time.RFC3339Nano)
+		}
+		if sink.Ordering {
+			msg.OrderingKey = md.GetSourceId()
+		}
+	}
+	return msg, nil
+}
+
+func (sink *pubSubSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+
+	// Publish everything first, then wait for the results
+	results := make([]*pubsub.PublishResult, len(rows))
+	orderingKeys := make([]string, len(rows))
+	for k, row := range rows {
+		msg, err := sink.message(row)
+		if err != nil {
+			return err
+		}
+		results[k] = sink.topic.Publish(ctx, msg)
+		orderingKeys[k] = msg.OrderingKey
+	}
+
+	for k, result := range results {
+		_, err := result.Get(ctx)
+		if err != nil {
+			// A failed ordered publish pauses its key until resumed
+			if orderingKeys[k] != "" {
+				sink.topic.ResumePublish(orderingKeys[k])
+			}
+			return fmt.Errorf("Error publishing message: %v", err)
+		}
+	}
+	return nil
+}
+
+func (sink *pubSubSink) Flush(ctx context.Context) error {
+	// Results are awaited in WriteRows, so there is nothing left to send
+	return nil
+}
+
+func (sink *pubSubSink) Health(ctx context.Context) error {
+	exists, err := sink.topic.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("Error checking topic: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("Topic %s does not exist", sink.Topic)
+	}
+	return nil
+}
+
+func (sink *pubSubSink) Close() error {
+	sink.topic.Stop()
+	err := sink.client.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing client: %v", err)
+	}
+	return nil
+}
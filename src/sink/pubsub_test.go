@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+// Runs against the Pub/Sub emulator, e.g.
+// gcloud beta emulators pubsub start --host-port=localhost:8085
+// PUBSUB_EMULATOR_HOST=localhost:8085 go test ./sink
+func TestPubSubSink(t *testing.T) {
+	if os.Getenv("PUBSUB_EMULATOR_HOST") == "" {
+		t.Skip("PUBSUB_EMULATOR_HOST not set")
+	}
+	ctx := context.Background()
+	project := "test-project"
+	id := "webhook-connector-test-" + time.Now().Format("20060102150405")
+
+	client, err := pubsub.NewClient(ctx, project)
+	if err != nil {
+		t.Fatalf("Failed to create Pub/Sub client: %v", err)
+	}
+	defer client.Close()
+	topic, err := client.CreateTopic(ctx, id)
+	if err != nil {
+		t.Fatalf("Failed to create topic: %v", err)
+	}
+	sub, err := client.CreateSubscription(ctx, id, pubsub.SubscriptionConfig{Topic: topic, EnableMessageOrdering: true})
+	if err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+
+	sink, err := NewPubSubSink(project, id, "json", true)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	event := &model.WebhookEvent{
+		Metadata: &model.Metadata{SourceId: "pubsub-test", ReceivedAt: timestamppb.Now()},
+		Event:    "{}",
+	}
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{event})
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	// Wait for the message
+	rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var received *pubsub.Message
+	err = sub.Receive(rctx, func(_ context.Context, msg *pubsub.Message) {
+		msg.Ack()
+		received = msg
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("Failed to receive: %v", err)
+	}
+	if received == nil {
+		t.Fatal("No message received")
+	}
+	if received.Attributes["source_id"] != "pubsub-test" || received.OrderingKey != "pubsub-test" {
+		t.Fatalf("Unexpected attributes %v and ordering key %s", received.Attributes, received.OrderingKey)
+	}
+}
@@ -0,0 +1,113 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "redis_stream",
+		Description: "Appends every event to a Redis stream.",
+		Params: []conf.Param{
+			{Name: "url", Type: conf.ParamString, Required: true, Description: "redis:// or rediss:// url."},
+			{Name: "stream", Type: conf.ParamString, Required: true, Description: "Template."},
+			{Name: "max_len", Type: conf.ParamInteger, Default: 0, Description: "0 disables trimming."},
+			{Name: "exact_trim", Type: conf.ParamBool, Default: false},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewRedisStreamSink(
+			config.Config["url"].(string),
+			config.Config["stream"].(string),
+			int64(config.IntParam("max_len", 0)),
+			config.BoolParam("exact_trim", false),
+		)
+	})
+}
+
+// Redis Streams Sink
+/*
+	Appends every event to a stream with XADD. The stream name is a template
+	(see template.go), so events can be spread over one stream per source.
+
+	Entries carry the event as JSON in the "event" field, next to the
+	metadata fields. With maxLen set, streams are trimmed on every add,
+	approximately unless exactTrim is set.
+*/
+type redisStreamSink struct {
+	Stream    string
+	MaxLen    int64
+	ExactTrim bool
+	client    *redis.Client
+}
+
+func NewRedisStreamSink(url string, stream string, maxLen int64, exactTrim bool) (Sink, error) {
+
+	var sink *redisStreamSink
+
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return sink, fmt.Errorf("Invalid Redis url: %v", err)
+	}
+
+	sink = &redisStreamSink{
+		Stream:    stream,
+		MaxLen:    maxLen,
+		ExactTrim: exactTrim,
+		client:    redis.NewClient(opts),
+	}
+	return sink, nil
+}
+
+func (sink *redisStreamSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+
+	// Send all entries in a single round trip
+	pipe := sink.client.Pipeline()
+	for _, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		values := map[string]interface{}{"event": encoded}
+		for key, value := range rowAttributes(row) {
+			values[key] = value
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: renderTemplate(sink.Stream, rowPlaceholders(row)),
+			MaxLen: sink.MaxLen,
+			Approx: !sink.ExactTrim,
+			Values: values,
+		})
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("Error adding stream entries: %v", err)
+	}
+	return nil
+}
+
+func (sink *redisStreamSink) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (sink *redisStreamSink) Health(ctx context.Context) error {
+	err := sink.client.Ping(ctx).Err()
+	if err != nil {
+		return fmt.Errorf("Error pinging Redis: %v", err)
+	}
+	return nil
+}
+
+func (sink *redisStreamSink) Close() error {
+	err := sink.client.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing client: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+// recordingHook captures pipelined commands instead of sending them, and
+// fails them with err when set
+type recordingHook struct {
+	cmds [][]interface{}
+	err  error
+}
+
+func (h *recordingHook) DialHook(next redis.DialHook) redis.DialHook          { return next }
+func (h *recordingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+func (h *recordingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.cmds = append(h.cmds, cmd.Args())
+		}
+		return h.err
+	}
+}
+
+func newRecordedRedisSink(t *testing.T, stream string, maxLen int64, exactTrim bool) (Sink, *recordingHook) {
+	sink, err := NewRedisStreamSink("redis://localhost:6379/0", stream, maxLen, exactTrim)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	hook := &recordingHook{}
+	sink.(*redisStreamSink).client.AddHook(hook)
+	return sink, hook
+}
+
+// xaddFields returns the fields of a recorded XADD, which follow the "*" id
+func xaddFields(t *testing.T, args []interface{}) map[string]string {
+	for i, arg := range args {
+		if arg != "*" {
+			continue
+		}
+		fields := map[string]string{}
+		for j := i + 1; j+1 < len(args); j += 2 {
+			fields[fmt.Sprint(args[j])] = fmt.Sprintf("%s", args[j+1])
+		}
+		return fields
+	}
+	t.Fatalf("No entry id in %v", args)
+	return nil
+}
+
+func TestRedisStreamSinkEntries(t *testing.T) {
+	rows := []protoreflect.ProtoMessage{
+		&model.WebhookEvent{Metadata: &model.Metadata{SourceId: "a", SourceName: "Orders"}, Event: "{\"n\":1}"},
+		&model.WebhookEvent{Metadata: &model.Metadata{SourceId: "b"}, Event: "{\"n\":2}"},
+	}
+
+	// One stream per source, trimmed approximately
+	sink, hook := newRecordedRedisSink(t, "events:{source_id}", 100, false)
+	if err := sink.WriteRows(context.Background(), rows); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if len(hook.cmds) != 2 {
+		t.Fatalf("Expected 2 commands in one pipeline, got %v", hook.cmds)
+	}
+	want := "[xadd events:a maxlen ~ 100 *]"
+	if got := fmt.Sprint(hook.cmds[0][:6]); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+	if got := fmt.Sprint(hook.cmds[1][1]); got != "events:b" {
+		t.Fatalf("Expected stream events:b, got %s", got)
+	}
+	fields := xaddFields(t, hook.cmds[0])
+	if fields["source_id"] != "a" || fields["source_name"] != "Orders" {
+		t.Fatalf("Expected the metadata fields, got %v", fields)
+	}
+	if !strings.Contains(fields["event"], "\"event\":\"{\\\"n\\\":1}\"") {
+		t.Fatalf("Expected the event as JSON, got %s", fields["event"])
+	}
+
+	// Exact trimming
+	sink, hook = newRecordedRedisSink(t, "events", 100, true)
+	if err := sink.WriteRows(context.Background(), rows[:1]); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	want = "[xadd events maxlen 100 *]"
+	if got := fmt.Sprint(hook.cmds[0][:5]); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+
+	// No trimming
+	sink, hook = newRecordedRedisSink(t, "events", 0, false)
+	if err := sink.WriteRows(context.Background(), rows[:1]); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	want = "[xadd events *]"
+	if got := fmt.Sprint(hook.cmds[0][:3]); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestRedisStreamSinkErrors(t *testing.T) {
+	_, err := NewRedisStreamSink("http://localhost:6379", "events", 0, false)
+	if err == nil || !strings.Contains(err.Error(), "Invalid Redis url") {
+		t.Fatalf("Expected an invalid url error, got %v", err)
+	}
+
+	sink, hook := newRecordedRedisSink(t, "events", 0, false)
+	hook.err = errors.New("READONLY You can't write against a read only replica.")
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}})
+	if err == nil || !strings.Contains(err.Error(), "Error adding stream entries") {
+		t.Fatalf("Expected the failed pipeline to be reported, got %v", err)
+	}
+}
+
+// Runs against a Redis server, e.g.
+// docker run -p 6379:6379 redis:7
+// REDIS_URL=redis://localhost:6379/0 go test ./sink
+func TestRedisStreamSink(t *testing.T) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL not set")
+	}
+	ctx := context.Background()
+	stream := "webhook-connector-test-" + time.Now().Format("20060102150405")
+
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("Invalid REDIS_URL: %v", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+	defer client.Del(ctx, stream+":abc")
+
+	sink, err := NewRedisStreamSink(url, stream+":{source_id}", 2, true)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+	if err := sink.Health(ctx); err != nil {
+		t.Fatalf("Expected the sink to be healthy, got %v", err)
+	}
+
+	var rows []protoreflect.ProtoMessage
+	for i := 0; i < 3; i++ {
+		rows = append(rows, &model.WebhookEvent{
+			Metadata: &model.Metadata{SourceId: "abc"},
+			Event:    fmt.Sprintf("{\"n\":%d}", i),
+		})
+	}
+	if err := sink.WriteRows(ctx, rows); err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	// Trimmed to the last two entries
+	entries, err := client.XRange(ctx, stream+":abc", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[1].Values["source_id"] != "abc" || !strings.Contains(fmt.Sprint(entries[1].Values["event"]), "n\\\":2") {
+		t.Fatalf("Unexpected entry %v", entries[1].Values)
+	}
+}
@@ -0,0 +1,451 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rowEncoder writes rows of column values in a file format
+type rowEncoder interface {
+	Write(values []interface{}) error
+	Close() error // Finishes the file, without closing the underlying writer
+}
+
+// rollingFile writes encoded rows into a directory, starting a new file once
+// the current one reaches maxBytes or gets older than maxAge.
+/*
+	Files are written under a hidden .inprogress name and only renamed to
+	{prefix}-{timestamp}-{id}{extension} once complete, so readers listing the
+	directory (Spark, BigQuery external tables, ...) never see partial files.
+
+	Rows written with WriteDay go to a file per UTC day instead, named
+	{prefix}-{day}-{id}{extension}. The file is completed when rows of
+	another day arrive, or once the day is over.
+
+	Encoders that buffer (e.g. parquet row groups) only count towards maxBytes
+	once they flush, so files can grow somewhat past it.
+
+	In-progress files of the same prefix and extension left behind by a
+	crash can't be completed, so they are removed, with a log line, when the
+	files are opened again. Files being written by this process are kept,
+	but a directory must not be shared with sinks of other processes.
+
+	With an uploader, completed files are uploaded and then removed from the
+	directory. Files that failed to upload, or were completed before a
+	restart, stay in the directory and are retried every uploadInterval.
+*/
+type rollingFile struct {
+	Directory  string
+	Prefix     string
+	Extension  string
+	maxBytes   int64
+	maxAge     time.Duration
+	newEncoder func(io.Writer) (rowEncoder, error)
+
+	mu      sync.Mutex
+	file    *os.File
+	written *countingWriter
+	encoder rowEncoder
+	name    string
+	day     string // "2006-01-02", or "" when not rolling by day
+	opened  time.Time
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	completed    *regexp.Regexp // Names of completed files
+	uploader     objectUploader
+	uploadPrefix string
+	contentType  string
+	rolled       chan struct{}
+	cancelUpload context.CancelFunc
+}
+
+const uploadInterval = 30 * time.Second
+
+// claimedFiles are the paths being written or uploaded by rolling files of
+// this process, so another one sharing the directory leaves them alone
+var claimedFiles = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: map[string]bool{}}
+
+func claimFile(path string) bool {
+	claimedFiles.Lock()
+	defer claimedFiles.Unlock()
+	if claimedFiles.paths[path] {
+		return false
+	}
+	claimedFiles.paths[path] = true
+	return true
+}
+
+func releaseFile(path string) {
+	claimedFiles.Lock()
+	defer claimedFiles.Unlock()
+	delete(claimedFiles.paths, path)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newRollingFile(directory string, prefix string, extension string, maxBytes int64, maxAge time.Duration, newEncoder func(io.Writer) (rowEncoder, error)) (*rollingFile, error) {
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create directory: %v", err)
+	}
+
+	r := &rollingFile{
+		Directory:  directory,
+		Prefix:     prefix,
+		Extension:  extension,
+		maxBytes:   maxBytes,
+		maxAge:     maxAge,
+		newEncoder: newEncoder,
+		done:       make(chan struct{}),
+		completed: regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) +
+			`-(\d{8}T\d{6}\.\d{9}|\d{4}-\d{2}-\d{2})-[0-9a-f]{8}` + regexp.QuoteMeta(extension) + `$`),
+	}
+	err = r.removeLeftovers()
+	if err != nil {
+		return nil, err
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r, nil
+}
+
+// removeLeftovers removes the in-progress files of an earlier process
+func (r *rollingFile) removeLeftovers() error {
+	entries, err := os.ReadDir(r.Directory)
+	if err != nil {
+		return fmt.Errorf("Failed to list directory: %v", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".inprogress") {
+			continue
+		}
+		if !r.completed.MatchString(strings.TrimSuffix(strings.TrimPrefix(name, "."), ".inprogress")) {
+			continue
+		}
+		leftover := filepath.Join(r.Directory, name)
+		if !claimFile(leftover) {
+			continue // Still being written
+		}
+		info, err := entry.Info()
+		if err == nil {
+			log.Printf("Removing incomplete file %s (%d bytes) left by an earlier run", leftover, info.Size())
+			err = os.Remove(leftover)
+		}
+		releaseFile(leftover)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("Failed to remove incomplete file %s: %v", leftover, err)
+		}
+	}
+	return nil
+}
+
+// uploadCompletedFiles starts uploading the completed files to options.UploadTo, if set
+func uploadCompletedFiles(r *rollingFile, options FileSinkOptions, contentType string) error {
+	uploader, prefix, err := newFileUploader(options)
+	if err != nil || uploader == nil {
+		return err
+	}
+	r.startUploads(uploader, prefix, contentType)
+	return nil
+}
+
+func (r *rollingFile) startUploads(uploader objectUploader, prefix string, contentType string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.uploader = uploader
+	r.uploadPrefix = prefix
+	r.contentType = contentType
+	r.rolled = make(chan struct{}, 1)
+	r.cancelUpload = cancel
+	r.wg.Add(1)
+	go r.runUploads(ctx)
+}
+
+func (r *rollingFile) runUploads(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(uploadInterval)
+	defer ticker.Stop()
+
+	for {
+		// Files completed before a restart are uploaded right away
+		err := r.upload(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to upload files from %s: %v", r.Directory, err)
+		}
+		select {
+		case <-r.done:
+			return
+		case <-r.rolled:
+		case <-ticker.C:
+		}
+	}
+}
+
+// upload uploads and removes every completed file in the directory
+func (r *rollingFile) upload(ctx context.Context) error {
+	entries, err := os.ReadDir(r.Directory)
+	if err != nil {
+		return fmt.Errorf("Failed to list directory: %v", err)
+	}
+	var firstErr error
+	for _, entry := range entries {
+		if !r.completed.MatchString(entry.Name()) {
+			continue
+		}
+		completed := filepath.Join(r.Directory, entry.Name())
+		if !claimFile(completed) {
+			continue // Another sink on the directory is uploading it
+		}
+		err := r.uploadFile(ctx, completed)
+		releaseFile(completed)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (r *rollingFile) uploadFile(ctx context.Context, filePath string) error {
+	file, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Uploaded by another sink in the meantime
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to open %s: %v", filePath, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("Failed to stat %s: %v", filePath, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, batchUploadTimeout)
+	defer cancel()
+	name := path.Join(r.uploadPrefix, filepath.Base(filePath))
+	err = r.uploader.Upload(ctx, name, r.contentType, file, info.Size())
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filePath)
+	if err != nil {
+		return fmt.Errorf("Failed to remove uploaded file %s: %v", filePath, err)
+	}
+	return nil
+}
+
+func (r *rollingFile) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			var err error
+			switch {
+			case r.file == nil:
+			case r.maxAge > 0 && time.Since(r.opened) >= r.maxAge:
+				err = r.roll()
+			case r.day != "" && r.day < time.Now().UTC().Format(dayFormat):
+				err = r.roll()
+			}
+			r.mu.Unlock()
+			if err != nil {
+				log.Printf("Failed to roll file in %s: %v", r.Directory, err)
+			}
+		}
+	}
+}
+
+func (r *rollingFile) inProgressPath() string {
+	return filepath.Join(r.Directory, "."+r.name+".inprogress")
+}
+
+const dayFormat = "2006-01-02"
+
+func (r *rollingFile) open(day string) error {
+	r.name = fmt.Sprintf("%s-%s-%s%s", r.Prefix, time.Now().UTC().Format(segmentTimeFormat), uuid.NewString()[:8], r.Extension)
+	if day != "" {
+		r.name = fmt.Sprintf("%s-%s-%s%s", r.Prefix, day, uuid.NewString()[:8], r.Extension)
+	}
+	claimFile(r.inProgressPath())
+	file, err := os.OpenFile(r.inProgressPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		releaseFile(r.inProgressPath())
+		return fmt.Errorf("Failed to create file: %v", err)
+	}
+	r.written = &countingWriter{w: file}
+	encoder, err := r.newEncoder(r.written)
+	if err != nil {
+		file.Close()
+		os.Remove(r.inProgressPath())
+		releaseFile(r.inProgressPath())
+		return fmt.Errorf("Failed to create encoder: %v", err)
+	}
+	r.file = file
+	r.encoder = encoder
+	r.day = day
+	r.opened = time.Now()
+	return nil
+}
+
+// roll completes the current file and publishes it under its final name
+func (r *rollingFile) roll() error {
+	if r.file == nil {
+		return nil
+	}
+	file := r.file
+	r.file = nil
+	defer releaseFile(r.inProgressPath())
+
+	err := r.encoder.Close()
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to complete file %s: %v", r.name, err)
+	}
+
+	err = os.Rename(r.inProgressPath(), filepath.Join(r.Directory, r.name))
+	if err != nil {
+		return fmt.Errorf("Failed to rename file %s: %v", r.name, err)
+	}
+
+	// Wake up the uploads, unless they're already due
+	if r.rolled != nil {
+		select {
+		case r.rolled <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (r *rollingFile) Write(rows [][]interface{}) error {
+	return r.WriteDay("", rows)
+}
+
+// WriteDay writes rows into the file of a UTC day, e.g. "2024-02-03"
+func (r *rollingFile) WriteDay(day string, rows [][]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return fmt.Errorf("Files in %s are closed", r.Directory)
+	}
+	if r.file != nil && r.day != day {
+		err := r.roll()
+		if err != nil {
+			return err
+		}
+	}
+	if r.file == nil {
+		err := r.open(day)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, values := range rows {
+		err := r.encoder.Write(values)
+		if err != nil {
+			return fmt.Errorf("Failed to encode row: %v", err)
+		}
+	}
+
+	if r.maxBytes > 0 && r.written.n >= r.maxBytes {
+		return r.roll()
+	}
+	return nil
+}
+
+// Flush completes the current file, so rows written so far become visible
+func (r *rollingFile) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.roll()
+}
+
+// Health checks that the directory is still writable, and the bucket
+// reachable when uploading
+func (r *rollingFile) Health(ctx context.Context) error {
+	info, err := os.Stat(r.Directory)
+	if err != nil {
+		return fmt.Errorf("Failed to stat directory: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", r.Directory)
+	}
+	if info.Mode().Perm()&0200 == 0 {
+		return fmt.Errorf("Directory %s is not writable", r.Directory)
+	}
+	if r.uploader != nil {
+		return r.uploader.Health(ctx)
+	}
+	return nil
+}
+
+// Close completes the current file, uploads the completed files and stops
+// rolling. Files that fail to upload are left for the next run.
+func (r *rollingFile) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	err := r.roll()
+	r.mu.Unlock()
+
+	close(r.done)
+	if r.uploader == nil {
+		r.wg.Wait()
+		return err
+	}
+
+	// Stop an upload in progress and retry it along with the last file
+	r.cancelUpload()
+	r.wg.Wait()
+	uploadErr := r.upload(context.Background())
+	if err == nil && uploadErr != nil {
+		err = fmt.Errorf("Failed to upload files, they are kept in %s: %v", r.Directory, uploadErr)
+	}
+	closeErr := r.uploader.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
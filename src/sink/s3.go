@@ -0,0 +1,146 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "s3",
+		Description: "Archives batches of events as JSON lines objects in S3 compatible storage, partitioned by source and hour.",
+		Params: []conf.Param{
+			{Name: "bucket", Type: conf.ParamString, Required: true},
+			{Name: "endpoint", Type: conf.ParamString, Default: "s3.amazonaws.com"},
+			{Name: "region", Type: conf.ParamString},
+			{Name: "prefix", Type: conf.ParamString},
+			{Name: "access_key_id", Type: conf.ParamString, Description: "Credentials are taken from the environment when not set."},
+			{Name: "secret_access_key", Type: conf.ParamString},
+			{Name: "use_ssl", Type: conf.ParamBool, Default: true},
+			{Name: "compression", Type: conf.ParamString, Default: "gzip", Options: []string{"none", "gzip"}},
+			{Name: "max_batch_bytes", Type: conf.ParamInteger, Default: 8 << 20, Positive: true},
+			{Name: "max_batch_age_seconds", Type: conf.ParamInteger, Default: 60, Positive: true},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewS3Sink(
+			config.StringParam("endpoint", ""),
+			config.StringParam("region", ""),
+			config.Config["bucket"].(string),
+			config.StringParam("prefix", ""),
+			config.StringParam("access_key_id", ""),
+			config.StringParam("secret_access_key", ""),
+			config.BoolParam("use_ssl", false),
+			config.StringParam("compression", ""),
+			config.IntParam("max_batch_bytes", 0),
+			time.Duration(config.IntParam("max_batch_age_seconds", 0))*time.Second,
+		)
+	})
+}
+
+// S3 Sink
+/*
+	Archives events as newline-delimited JSON objects in any S3 compatible
+	object storage (AWS S3, MinIO, ...).
+
+	Objects are partitioned Hive style, so they can be queried as an external table:
+	{prefix}/source_id={id}/dt={YYYY-MM-DD}/hour={HH}/{timestamp}-{uuid}.jsonl[.gz]
+*/
+type s3Sink struct {
+	Bucket      string
+	Prefix      string
+	Compression string
+	uploader    objectUploader
+	batcher     *batcher
+}
+
+func NewS3Sink(endpoint string, region string, bucket string, prefix string, accessKeyID string, secretAccessKey string, useSSL bool, compression string, maxBatchBytes int, maxBatchAge time.Duration) (Sink, error) {
+
+	var sink *s3Sink
+
+	uploader, err := newS3Uploader(bucket, S3Options{
+		Endpoint:        endpoint,
+		Region:          region,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		UseSSL:          useSSL,
+	})
+	if err != nil {
+		return sink, err
+	}
+
+	sink = &s3Sink{
+		Bucket:      bucket,
+		Prefix:      prefix,
+		Compression: compression,
+		uploader:    uploader,
+	}
+	sink.batcher = newBatcher(maxBatchBytes, maxBatchAge, sink.putObject)
+
+	return sink, nil
+}
+
+// partition returns the Hive style key prefix for an event
+func (sink *s3Sink) partition(sourceID string, receivedAt time.Time) string {
+	return path.Join(
+		sink.Prefix,
+		fmt.Sprintf("source_id=%s", sourceID),
+		fmt.Sprintf("dt=%s", receivedAt.Format("2006-01-02")),
+		fmt.Sprintf("hour=%s", receivedAt.Format("15")),
+	)
+}
+
+func (sink *s3Sink) putObject(ctx context.Context, partition string, b *batch) error {
+	data, err := compress(b.data.Bytes(), sink.Compression)
+	if err != nil {
+		return fmt.Errorf("Error compressing batch: %v", err)
+	}
+
+	name := fmt.Sprintf("%d-%s.jsonl%s", time.Now().UnixNano(), uuid.NewString(), compressionExtension(sink.Compression))
+	contentType := "application/x-ndjson"
+	if sink.Compression == "gzip" {
+		contentType = "application/gzip"
+	}
+	return sink.uploader.Upload(ctx, path.Join(partition, name), contentType, bytes.NewReader(data), int64(len(data)))
+}
+
+func (sink *s3Sink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	for _, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		sourceID, receivedAt := rowMetadata(row)
+		err = sink.batcher.Add(ctx, sink.partition(sourceID, receivedAt), encoded)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sink *s3Sink) Flush(ctx context.Context) error {
+	return sink.batcher.Flush(ctx)
+}
+
+func (sink *s3Sink) Health(ctx context.Context) error {
+	return sink.uploader.Health(ctx)
+}
+
+// Close closes the client even if the pending batches fail to flush
+func (sink *s3Sink) Close() error {
+	var flushErr error
+	err := sink.batcher.Close()
+	if err != nil {
+		flushErr = fmt.Errorf("Error flushing pending batches: %v", err)
+	}
+	return errors.Join(flushErr, sink.uploader.Close())
+}
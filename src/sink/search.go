@@ -0,0 +1,199 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+	"github.com/altxtech/webhook-connector/src/utils"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "search",
+		Description: "Indexes events into daily Elasticsearch or OpenSearch indices.",
+		Params: []conf.Param{
+			{Name: "url", Type: conf.ParamString, Required: true},
+			{Name: "index", Type: conf.ParamString, Required: true, Description: "Index name prefix."},
+			{Name: "id_path", Type: conf.ParamString, Description: "JSON path of the document id in the payload."},
+			{Name: "username", Type: conf.ParamString},
+			{Name: "password", Type: conf.ParamString},
+			{Name: "api_key", Type: conf.ParamString},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewSearchSink(SearchSinkOptions{
+			URL:      config.Config["url"].(string),
+			Index:    config.Config["index"].(string),
+			IDPath:   config.StringParam("id_path", ""),
+			Username: config.StringParam("username", ""),
+			Password: config.StringParam("password", ""),
+			APIKey:   config.StringParam("api_key", ""),
+		})
+	})
+}
+
+// Elasticsearch / OpenSearch Sink
+/*
+	Indexes events through the _bulk API into daily indices named
+	{index}-YYYY.MM.DD after the event's receive date.
+
+	When idPath is set, the document id is taken from that JSON path in the
+	payload (e.g. the provider's event id), so redelivered webhooks overwrite
+	their earlier copy instead of being indexed twice. Events without it get
+	an id generated by the cluster.
+*/
+type SearchSinkOptions struct {
+	URL      string
+	Index    string
+	IDPath   string
+	Username string
+	Password string
+	APIKey   string
+}
+
+type searchSink struct {
+	Options SearchSinkOptions
+	client  *http.Client
+}
+
+func NewSearchSink(options SearchSinkOptions) (Sink, error) {
+	sink := &searchSink{
+		Options: options,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	sink.Options.URL = strings.TrimRight(options.URL, "/")
+	return sink, nil
+}
+
+func (sink *searchSink) indexName(receivedAt time.Time) string {
+	return fmt.Sprintf("%s-%s", sink.Options.Index, receivedAt.Format("2006.01.02"))
+}
+
+type bulkAction struct {
+	Index struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id,omitempty"`
+	} `json:"index"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func (sink *searchSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+
+	// Build the newline-delimited bulk body
+	var body bytes.Buffer
+	for _, row := range rows {
+		encoded, err := encodeRow(row)
+		if err != nil {
+			return err
+		}
+		_, receivedAt := rowMetadata(row)
+
+		var action bulkAction
+		action.Index.Index = sink.indexName(receivedAt)
+		if event, ok := row.(*model.WebhookEvent); ok && sink.Options.IDPath != "" {
+			id, ok := utils.LookupJSONPath([]byte(event.GetEvent()), sink.Options.IDPath)
+			if ok && id != nil {
+				action.Index.ID = utils.JSONValueString(id)
+			}
+		}
+		actionLine, err := json.Marshal(action)
+		if err != nil {
+			return fmt.Errorf("Error marshalling bulk action: %v", err)
+		}
+		body.Write(actionLine)
+		body.WriteByte('\n')
+		body.Write(encoded)
+		body.WriteByte('\n')
+	}
+
+	req, err := sink.newRequest(ctx, http.MethodPost, "/_bulk", &body)
+	if err != nil {
+		return fmt.Errorf("Error creating bulk request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending bulk request: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error reading bulk response: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Bulk request failed with status %d: %s", resp.StatusCode, data)
+	}
+
+	// The request can succeed while individual documents fail
+	var result bulkResponse
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		return fmt.Errorf("Error parsing bulk response: %v", err)
+	}
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, status := range item {
+				if status.Status >= 300 {
+					return fmt.Errorf("Failed to index document (status %d): %s", status.Status, status.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (sink *searchSink) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, sink.Options.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if sink.Options.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+sink.Options.APIKey)
+	} else if sink.Options.Username != "" {
+		req.SetBasicAuth(sink.Options.Username, sink.Options.Password)
+	}
+	return req, nil
+}
+
+func (sink *searchSink) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Health requests the cluster root, which answers with the cluster info
+func (sink *searchSink) Health(ctx context.Context) error {
+	req, err := sink.newRequest(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return fmt.Errorf("Error creating request: %v", err)
+	}
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error reaching cluster: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Cluster answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (sink *searchSink) Close() error {
+	sink.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,80 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func TestSearchSinkBulkRequest(t *testing.T) {
+
+	// Fake cluster recording the bulk actions it receives
+	var actions []bulkAction
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for line := 0; scanner.Scan(); line++ {
+			if line%2 != 0 {
+				continue // Document line
+			}
+			var action bulkAction
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("Invalid action line: %v", err)
+			}
+			actions = append(actions, action)
+		}
+		w.Write([]byte(`{"errors": false, "items": []}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSearchSink(SearchSinkOptions{URL: server.URL, Index: "webhooks", IDPath: "id"})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	receivedAt := timestamppb.New(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC))
+	rows := []protoreflect.ProtoMessage{
+		&model.WebhookEvent{Metadata: &model.Metadata{ReceivedAt: receivedAt}, Event: `{"id": "evt_1"}`},
+		&model.WebhookEvent{Metadata: &model.Metadata{ReceivedAt: receivedAt}, Event: `{}`},
+	}
+	err = sink.WriteRows(context.Background(), rows)
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 bulk actions, got %d", len(actions))
+	}
+	if actions[0].Index.Index != "webhooks-2024.02.03" || actions[0].Index.ID != "evt_1" {
+		t.Fatalf("Unexpected first action %+v", actions[0])
+	}
+	if actions[1].Index.ID != "" {
+		t.Fatalf("Expected no id for an event without one, got %s", actions[1].Index.ID)
+	}
+}
+
+func TestSearchSinkItemErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": true, "items": [{"index": {"status": 400, "error": {"type": "mapper_parsing_exception"}}}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewSearchSink(SearchSinkOptions{URL: server.URL, Index: "webhooks"})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{&model.WebhookEvent{Event: "{}"}})
+	if err == nil {
+		t.Fatal("Expected an error for a rejected document")
+	}
+}
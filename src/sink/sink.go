@@ -4,24 +4,46 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"sync"
+	"time"
 
-	"cloud.google.com/go/bigquery/storage/managedwriter"
 	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
 	conf "github.com/altxtech/webhook-connector/src/configurations"
-	"github.com/altxtech/webhook-connector/src/model"
-	"google.golang.org/protobuf/encoding/protojson"
-	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 // Sink type
+/*
+	The context passed to WriteRows, Flush and Health carries the caller's
+	deadline (the webhook request, a probe, shutdown) down to the destination.
+*/
 type Sink interface {
-	 WriteRows([]protoreflect.ProtoMessage) error
+	 WriteRows(context.Context, []protoreflect.ProtoMessage) error
+	 Flush(context.Context) error // Writes out buffered rows, if the sink buffers
+	 Health(context.Context) error // Checks that the destination is reachable
 	 Close() error
 }
 
+// Factory creates a sink from a validated configuration, with the schema
+// defaults already applied.
+type Factory func(config conf.Sink) (Sink, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register makes a sink type available to NewSink and to configuration
+// validation. Sink implementations call it from init.
+func Register(schema conf.SinkSchema, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	conf.RegisterSinkSchema(schema)
+	factories[schema.Type] = factory
+}
+
 func NewSink(config conf.Sink) (Sink, error){
 
 	// Check if config is valid
@@ -31,159 +53,55 @@ func NewSink(config conf.Sink) (Sink, error){
 	}
 
 	// Create the appropriate sink based on type
-	switch config.Type{
-	case "jsonl":
-		path := config.Config["file_path"].(string) 
-		return NewJSONLSink(path), nil
-	case "bigquery":
-		project := config.Config["project"].(string)
-		dataset := config.Config["dataset"].(string)
-		table := config.Config["table"].(string)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to retrieve BigQueryWriteClient: %v", err)
-		}
-		s, err := NewBigQuerySink(project, dataset, table, "webhook-connector")
-		if err != nil {
-			return s, fmt.Errorf("Failed to create bigQuerySink: %v", err)
-		}
-		return s, nil
-	default:
+	factoriesMu.RLock()
+	factory, ok := factories[config.Type]
+	factoriesMu.RUnlock()
+	if !ok {
 		return nil, fmt.Errorf("Unsuported sink type '%s'", config.Type)
 	}
-}
-
 
-// Helpers
-func getDescriptor(message protoreflect.ProtoMessage) *descriptorpb.DescriptorProto  {
-	descriptor, err := adapt.NormalizeDescriptor(message.ProtoReflect().Descriptor())
+	s, err := factory(config.WithDefaults())
 	if err != nil {
-		log.Fatal("NormalizeDescriptor: ", err)
+		return nil, fmt.Errorf("Failed to create %s sink: %v", config.Type, err)
 	}
-	return descriptor
-}
-
-// Local file sink (for testing)
-type JSONLSink struct {
-	Path string
+	return s, nil
 }
-func NewJSONLSink (path string) JSONLSink {
-	return JSONLSink{
-		Path: path,
-	}
-}
-func (sink JSONLSink) WriteRows(rows []protoreflect.ProtoMessage) error {
-
-	/*
-		TODO:
-		This is a doo doo implementation because we have to reopen and close the file every time we write a new batch of rows.
-		
-		This is good enough for testing, tough. But should be improved in the unlikely scenario this gets used for real.
-	*/
 
-	file, err := os.OpenFile(sink.Path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-	if err != nil {
-		return fmt.Errorf("Failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	for _, row := range rows {
-		msg, err := protojson.Marshal(row)
-		if err != nil {
-			return err
-		}
-		msg = append(msg, '\n')
-		file.Write(msg)
-	}
-
-	return nil
-}
-func (sink JSONLSink) Close() error {
-	return nil
-}
 
-// BigQuery Sink
-type bigQuerySink struct {
-	Project string
-	Dataset string
-	Table string
-	Trace string
-	client *managedwriter.Client
-	stream *managedwriter.ManagedStream
-}
-
-func NewBigQuerySink( project string, dataset string, table string, trace string) (Sink, error) {
-	
-	var sink *bigQuerySink
-
-	// Create bigquery client
-	client, err := managedwriter.NewClient(context.Background(), project)
-	if err != nil {
-		return sink, fmt.Errorf("Error creating Bigquery Writer: %v", err)
-	}
-	
-
-	// Get the descriptor for the event message
-	/*
-		When I want to generalize to different many schemas...
-		Create a descriptor based on the schema configuration.
-	*/
-	m := &model.WebhookEvent{}
-	descriptor, err := adapt.NormalizeDescriptor(m.ProtoReflect().Descriptor())
-	if err != nil {
-		return sink, fmt.Errorf("Failed to get prot descriptor: %v", err)
-	}
-
-	// Create managed stream
-	tableName := fmt.Sprintf("projects/%s/datasets/%s/tables/%s", project, dataset, table)
-	stream, err := client.NewManagedStream(
-		context.Background(),
-		managedwriter.WithDestinationTable(tableName),
-		managedwriter.WithSchemaDescriptor(descriptor),
-	)
-	if err != nil {
-		return sink, fmt.Errorf("Failed to create managed stream: %v", err)
-	}
+// Helpers
 
-	// Construct the sink object
-	sink = &bigQuerySink{
-		Project: project,
-		Dataset: dataset,
-		Table: table,
-		Trace: trace,
-		client: client,
-		stream: stream,
+// fileSinkParams are the parameters shared by the rolling file sinks
+func fileSinkParams(maxFileAgeSeconds int) []conf.Param {
+	return []conf.Param{
+		{Name: "directory", Type: conf.ParamString, Required: true},
+		{Name: "file_prefix", Type: conf.ParamString, Default: "events"},
+		{Name: "columns", Type: conf.ParamColumns, Description: "Defaults to the WebhookEvent fields."},
+		{Name: "max_file_bytes", Type: conf.ParamInteger, Default: 128 << 20},
+		{Name: "max_file_age_seconds", Type: conf.ParamInteger, Default: maxFileAgeSeconds},
 	}
-
-	return sink, nil
 }
 
-
-
-func (sink *bigQuerySink) WriteRows(rows []protoreflect.ProtoMessage,) error {
-
-	// Encode the messages
-	encoded := make([][]byte, len(rows))
-	for k, v := range rows {
-		b, err := proto.Marshal(v)
-		if err != nil {
-			return fmt.Errorf("Error marshalling rows: %v", err)
-		}
-		encoded[k] = b
-	}
-
-	result, err := sink.stream.AppendRows(context.Background(), encoded)
-	_, err = result.GetResult(context.Background())
-	if err != nil {
-		return fmt.Errorf("Error appending rows: %v", err)
+func fileSinkOptions(config conf.Sink) FileSinkOptions {
+	return FileSinkOptions{
+		Directory:    config.Config["directory"].(string),
+		FilePrefix:   config.StringParam("file_prefix", ""),
+		Columns:      config.ColumnsParam("columns", DefaultColumns),
+		Compression:  config.StringParam("compression", ""),
+		MaxFileBytes: int64(config.IntParam("max_file_bytes", 0)),
+		MaxFileAge:   time.Duration(config.IntParam("max_file_age_seconds", 0)) * time.Second,
+		UploadTo:     config.StringParam("upload_to", ""),
+		S3: S3Options{
+			Endpoint: config.StringParam("s3_endpoint", ""),
+			Region:   config.StringParam("s3_region", ""),
+			UseSSL:   config.BoolParam("s3_use_ssl", false),
+		},
 	}
-
-	return nil
 }
-func (sink *bigQuerySink) Close() error {
 
-	err := sink.client.Close()
+func getDescriptor(message protoreflect.ProtoMessage) *descriptorpb.DescriptorProto  {
+	descriptor, err := adapt.NormalizeDescriptor(message.ProtoReflect().Descriptor())
 	if err != nil {
-		return fmt.Errorf("Error closing client: %v", err)
+		log.Fatal("NormalizeDescriptor: ", err)
 	}
-	return nil
+	return descriptor
 }
@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"testing"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+func TestRegisteredSinkTypes(t *testing.T) {
+	types := map[string]bool{}
+	for _, schema := range conf.SinkSchemas() {
+		types[schema.Type] = true
+	}
+	for _, sinkType := range []string{"jsonl", "bigquery", "s3", "gcs", "pubsub", "kafka", "redis_stream", "nats", "search", "amqp", "mqtt", "parquet", "avro", "csv", "stdout"} {
+		if !types[sinkType] {
+			t.Errorf("Sink type %s is not registered", sinkType)
+		}
+	}
+}
+
+func TestSinkValidation(t *testing.T) {
+	cases := []struct {
+		name  string
+		sink  conf.Sink
+		valid bool
+	}{
+		{"unknown type", conf.Sink{Type: "nope", Config: map[string]interface{}{}}, false},
+		{"missing required", conf.Sink{Type: "jsonl", Config: map[string]interface{}{}}, false},
+		{"wrong type", conf.Sink{Type: "jsonl", Config: map[string]interface{}{"file_path": 1.0}}, false},
+		{"not an option", conf.Sink{Type: "jsonl", Config: map[string]interface{}{"file_path": "a.jsonl", "fsync": "sometimes"}}, false},
+		{"not an integer", conf.Sink{Type: "jsonl", Config: map[string]interface{}{"file_path": "a.jsonl", "max_file_bytes": 1.5}}, false},
+		{"custom rule", conf.Sink{Type: "kafka", Config: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "key": "json_path"}}, false},
+		{"header key without header", conf.Sink{Type: "kafka", Config: map[string]interface{}{"brokers": "localhost:9092", "topic": "t", "key": "header"}}, false},
+		{"valid", conf.Sink{Type: "jsonl", Config: map[string]interface{}{"file_path": "a.jsonl", "max_file_bytes": float64(1024)}}, true},
+	}
+	for _, c := range cases {
+		err := c.sink.Validate()
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestSinkDefaults(t *testing.T) {
+	s := conf.Sink{Type: "stdout", Config: map[string]interface{}{"format": "cloud_logging"}}.WithDefaults()
+	if s.StringParam("format", "") != "cloud_logging" {
+		t.Fatalf("Defaults overrode a configured value: %v", s.Config)
+	}
+	if s.StringParam("severity", "") != "INFO" {
+		t.Fatalf("Default severity was not applied: %v", s.Config)
+	}
+}
@@ -0,0 +1,155 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func init() {
+	Register(conf.SinkSchema{
+		Type:        "stdout",
+		Description: "Prints every event as a single line of JSON for container log pipelines.",
+		Params: []conf.Param{
+			{Name: "format", Type: conf.ParamString, Default: "json", Options: []string{"json", "cloud_logging"}},
+			{Name: "severity", Type: conf.ParamString, Default: "INFO", Options: []string{"DEFAULT", "DEBUG", "INFO", "NOTICE", "WARNING", "ERROR", "CRITICAL", "ALERT", "EMERGENCY"}},
+			{Name: "labels", Type: conf.ParamStringMap, Description: "Cloud Logging labels, values are templates."},
+		},
+	}, func(config conf.Sink) (Sink, error) {
+		return NewStdoutSink(
+			config.StringParam("format", ""),
+			config.StringParam("severity", ""),
+			config.StringMapParam("labels"),
+		)
+	})
+}
+
+// Stdout Sink
+/*
+	Prints every event as a single line of JSON, for container log pipelines
+	(Cloud Run, Kubernetes logging agents, ...).
+
+	Format "json" prints the WebhookEvent as is. Format "cloud_logging"
+	prints a Cloud Logging structured entry: the payload is kept as a JSON
+	object under "event", with severity, timestamp and labels set in the
+	fields the logging agent recognizes. Label values are templates (see
+	template.go).
+
+	Every stdout sink writes through Stdout, so lines of different sinks
+	don't interleave.
+*/
+type stdoutSink struct {
+	Format   string // "json" or "cloud_logging"
+	Severity string
+	Labels   map[string]string
+	out      io.Writer
+}
+
+// Stdout writes to os.Stdout one call at a time. Anything else printing to
+// stdout, like gin's request logger, should write through it too.
+var Stdout io.Writer = stdoutWriter{}
+
+var stdoutMu sync.Mutex
+
+type stdoutWriter struct{}
+
+func (stdoutWriter) Write(data []byte) (int, error) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+	return os.Stdout.Write(data)
+}
+
+func NewStdoutSink(format string, severity string, labels map[string]string) (Sink, error) {
+	return &stdoutSink{
+		Format:   format,
+		Severity: severity,
+		Labels:   labels,
+		out:      Stdout,
+	}, nil
+}
+
+type cloudLoggingEntry struct {
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Time     string            `json:"time,omitempty"`
+	Labels   map[string]string `json:"logging.googleapis.com/labels,omitempty"`
+	Metadata json.RawMessage   `json:"metadata,omitempty"`
+	Event    json.RawMessage   `json:"event,omitempty"`
+}
+
+func (sink *stdoutSink) line(row protoreflect.ProtoMessage) ([]byte, error) {
+	if sink.Format != "cloud_logging" {
+		return encodeRow(row)
+	}
+
+	sourceID, receivedAt := rowMetadata(row)
+	entry := cloudLoggingEntry{
+		Severity: sink.Severity,
+		Message:  fmt.Sprintf("Webhook event from %s", sourceID),
+		Time:     receivedAt.Format(time.RFC3339Nano),
+		Labels:   map[string]string{"source_id": sourceID},
+	}
+	placeholders := rowPlaceholders(row)
+	for key, value := range sink.Labels {
+		entry.Labels[key] = renderTemplate(value, placeholders)
+	}
+
+	if event, ok := row.(*model.WebhookEvent); ok {
+		metadata, err := encodeRow(event.GetMetadata())
+		if err != nil {
+			return nil, err
+		}
+		entry.Metadata = metadata
+		// Payloads are validated as JSON on ingestion, but don't emit a broken line if one isn't
+		if json.Valid([]byte(event.GetEvent())) {
+			entry.Event = json.RawMessage(event.GetEvent())
+		}
+	}
+
+	// Compact keeps the entry on a single line whatever the payload's formatting
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("Error marshalling log entry: %v", err)
+	}
+	return encoded, nil
+}
+
+func (sink *stdoutSink) WriteRows(ctx context.Context, rows []protoreflect.ProtoMessage) error {
+	var data []byte
+	for _, row := range rows {
+		line, err := sink.line(row)
+		if err != nil {
+			return err
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	// One write per batch so lines of concurrent writes don't interleave
+	_, err := sink.out.Write(data)
+	if err != nil {
+		return fmt.Errorf("Error writing to stdout: %v", err)
+	}
+	return nil
+}
+
+func (sink *stdoutSink) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (sink *stdoutSink) Health(ctx context.Context) error {
+	return nil
+}
+
+func (sink *stdoutSink) Close() error {
+	return nil
+}
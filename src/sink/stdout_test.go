@@ -0,0 +1,91 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+func stdoutTestEvent(payload string) *model.WebhookEvent {
+	return &model.WebhookEvent{
+		Metadata: &model.Metadata{
+			SourceId:   "orders",
+			ReceivedAt: timestamppb.New(time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)),
+		},
+		Event: payload,
+	}
+}
+
+func TestStdoutSinkCloudLogging(t *testing.T) {
+	sink, err := NewStdoutSink("cloud_logging", "NOTICE", map[string]string{"source": "{source_id}"})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	var out bytes.Buffer
+	sink.(*stdoutSink).out = &out
+
+	err = sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{stdoutTestEvent("{\n  \"id\": 1\n}")})
+	if err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("Expected a single line, got %q", out.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON line, got %q", out.String())
+	}
+	labels, _ := entry["logging.googleapis.com/labels"].(map[string]interface{})
+	event, _ := entry["event"].(map[string]interface{})
+	if entry["severity"] != "NOTICE" || entry["time"] != "2024-02-03T04:05:06Z" || labels["source"] != "orders" || event["id"] != 1.0 {
+		t.Fatalf("Unexpected entry %v", entry)
+	}
+}
+
+func TestStdoutSinkSharesTheStdoutLock(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+	stdout := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = stdout }()
+
+	sink, err := NewStdoutSink("json", "INFO", nil)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+
+	// Another writer holds stdout, e.g. the request logger mid-line
+	stdoutMu.Lock()
+	done := make(chan error)
+	go func() {
+		done <- sink.WriteRows(context.Background(), []protoreflect.ProtoMessage{stdoutTestEvent("{}")})
+	}()
+	select {
+	case <-done:
+		stdoutMu.Unlock()
+		t.Fatal("Expected the write to wait for the stdout lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	stdoutMu.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Failed to write rows: %v", err)
+	}
+
+	written, err := os.ReadFile(file.Name())
+	if err != nil || !json.Valid(bytes.TrimSpace(written)) {
+		t.Fatalf("Expected the event on stdout, got %q (%v)", written, err)
+	}
+}
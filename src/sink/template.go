@@ -0,0 +1,51 @@
+package sink
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/altxtech/webhook-connector/src/model"
+)
+
+// Name templates
+/*
+	Several sinks build names (object names, topics, routing keys, ...) from
+	the event being written. Templates use {placeholder} tokens:
+
+	{source_id}, {source_name}     event metadata
+	{yyyy}, {mm}, {dd}, {hh}       receive time (UTC)
+	{date}                         receive date as YYYY-MM-DD
+
+	Unknown tokens are left untouched so sinks can fill their own ones in later.
+*/
+
+func rowPlaceholders(row protoreflect.ProtoMessage) map[string]string {
+	sourceID, receivedAt := rowMetadata(row)
+	sourceName := ""
+	if event, ok := row.(*model.WebhookEvent); ok {
+		sourceName = event.GetMetadata().GetSourceName()
+	}
+	return timePlaceholders(map[string]string{
+		"source_id":   sourceID,
+		"source_name": sourceName,
+	}, receivedAt)
+}
+
+func timePlaceholders(values map[string]string, t time.Time) map[string]string {
+	values["yyyy"] = t.Format("2006")
+	values["mm"] = t.Format("01")
+	values["dd"] = t.Format("02")
+	values["hh"] = t.Format("15")
+	values["date"] = t.Format("2006-01-02")
+	return values
+}
+
+func renderTemplate(template string, values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for key, value := range values {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
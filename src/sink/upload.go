@@ -0,0 +1,175 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	conf "github.com/altxtech/webhook-connector/src/configurations"
+)
+
+// objectUploader writes objects into a bucket. The gcs and s3 sinks upload
+// their batches through it, and the file sinks their completed files.
+type objectUploader interface {
+	Upload(ctx context.Context, name string, contentType string, data io.Reader, size int64) error
+	Health(ctx context.Context) error
+	Close() error
+}
+
+type gcsUploader struct {
+	Bucket string
+	client *storage.Client
+}
+
+func newGCSUploader(bucket string) (*gcsUploader, error) {
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("Error creating storage client: %v", err)
+	}
+	return &gcsUploader{Bucket: bucket, client: client}, nil
+}
+
+func (u *gcsUploader) Upload(ctx context.Context, name string, contentType string, data io.Reader, size int64) error {
+	w := u.client.Bucket(u.Bucket).Object(name).NewWriter(ctx)
+	w.ContentType = contentType
+	_, err := io.Copy(w, data)
+	if err != nil {
+		w.Close()
+		return fmt.Errorf("Error writing object %s: %v", name, err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("Error writing object %s: %v", name, err)
+	}
+	return nil
+}
+
+func (u *gcsUploader) Health(ctx context.Context) error {
+	_, err := u.client.Bucket(u.Bucket).Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("Error getting bucket attributes: %v", err)
+	}
+	return nil
+}
+
+func (u *gcsUploader) Close() error {
+	err := u.client.Close()
+	if err != nil {
+		return fmt.Errorf("Error closing client: %v", err)
+	}
+	return nil
+}
+
+// S3Options connect to S3 compatible storage. Credentials are taken from the
+// environment when AccessKeyID is empty.
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	UseSSL          bool
+}
+
+type s3Uploader struct {
+	Bucket string
+	client *minio.Client
+}
+
+func newS3Uploader(bucket string, options S3Options) (*s3Uploader, error) {
+	// Use static credentials when provided, otherwise look them up in the environment
+	var creds *credentials.Credentials
+	if options.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(options.AccessKeyID, options.SecretAccessKey, "")
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		})
+	}
+
+	client, err := minio.New(options.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: options.UseSSL,
+		Region: options.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error creating S3 client: %v", err)
+	}
+	return &s3Uploader{Bucket: bucket, client: client}, nil
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, name string, contentType string, data io.Reader, size int64) error {
+	_, err := u.client.PutObject(ctx, u.Bucket, name, data, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("Error putting object %s: %v", name, err)
+	}
+	return nil
+}
+
+func (u *s3Uploader) Health(ctx context.Context) error {
+	exists, err := u.client.BucketExists(ctx, u.Bucket)
+	if err != nil {
+		return fmt.Errorf("Error checking bucket: %v", err)
+	}
+	if !exists {
+		return fmt.Errorf("Bucket %s does not exist", u.Bucket)
+	}
+	return nil
+}
+
+func (u *s3Uploader) Close() error {
+	return nil
+}
+
+// uploadParams are the parameters of the file sinks that upload completed files
+func uploadParams() []conf.Param {
+	return []conf.Param{
+		{Name: "upload_to", Type: conf.ParamString, Description: "gs://bucket/prefix or s3://bucket/prefix. Completed files are uploaded there, then removed locally."},
+		{Name: "s3_endpoint", Type: conf.ParamString, Default: "s3.amazonaws.com"},
+		{Name: "s3_region", Type: conf.ParamString},
+		{Name: "s3_use_ssl", Type: conf.ParamBool, Default: true},
+	}
+}
+
+func validateUploadTo(s conf.Sink) error {
+	_, _, _, err := parseUploadTo(s.StringParam("upload_to", ""))
+	return err
+}
+
+// parseUploadTo splits gs://bucket/prefix or s3://bucket/prefix
+func parseUploadTo(uploadTo string) (scheme string, bucket string, prefix string, err error) {
+	if uploadTo == "" {
+		return "", "", "", nil
+	}
+	scheme, rest, ok := strings.Cut(uploadTo, "://")
+	if !ok || (scheme != "gs" && scheme != "s3") {
+		return "", "", "", errors.New("Parameter upload_to must start with gs:// or s3://.")
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", "", errors.New("Parameter upload_to must name a bucket.")
+	}
+	return scheme, bucket, strings.Trim(prefix, "/"), nil
+}
+
+// newFileUploader returns the uploader for upload_to, or nil if files stay local
+func newFileUploader(options FileSinkOptions) (objectUploader, string, error) {
+	scheme, bucket, prefix, err := parseUploadTo(options.UploadTo)
+	if err != nil || scheme == "" {
+		return nil, "", err
+	}
+	if scheme == "gs" {
+		uploader, err := newGCSUploader(bucket)
+		return uploader, prefix, err
+	}
+	uploader, err := newS3Uploader(bucket, options.S3)
+	return uploader, prefix, err
+}
@@ -1,10 +1,65 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
+	"strconv"
+	"strings"
 )
 
 func IsValidJSON(data []byte) bool {
     var js interface{}
     return json.Unmarshal(data, &js) == nil
 }
+
+// LookupJSONPath returns the value at a dot separated path in a JSON document.
+// Array elements are addressed by index, e.g. "data.items.0.id". Numbers are
+// returned as json.Number, so ids above 2^53 keep every digit.
+func LookupJSONPath(data []byte, path string) (interface{}, bool) {
+    var value interface{}
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    if decoder.Decode(&value) != nil {
+        return nil, false
+    }
+    return LookupPath(value, path)
+}
+
+// LookupPath is LookupJSONPath on an already decoded JSON value
+func LookupPath(value interface{}, path string) (interface{}, bool) {
+    if path == "" {
+        return value, true
+    }
+    for _, part := range strings.Split(path, ".") {
+        switch v := value.(type) {
+        case map[string]interface{}:
+            next, ok := v[part]
+            if !ok {
+                return nil, false
+            }
+            value = next
+        case []interface{}:
+            i, err := strconv.Atoi(part)
+            if err != nil || i < 0 || i >= len(v) {
+                return nil, false
+            }
+            value = v[i]
+        default:
+            return nil, false
+        }
+    }
+    return value, true
+}
+
+// JSONValueString renders a JSON value as plain text. Strings are returned
+// unquoted, everything else as its JSON encoding.
+func JSONValueString(value interface{}) string {
+    if s, ok := value.(string); ok {
+        return s
+    }
+    encoded, err := json.Marshal(value)
+    if err != nil {
+        return ""
+    }
+    return string(encoded)
+}
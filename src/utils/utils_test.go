@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	data := []byte(`{"data": {"customer": {"id": "cus_1"}, "items": [{"id": 7}]}}`)
+
+	cases := map[string]string{
+		"data.customer.id": "cus_1",
+		"data.items.0.id":  "7",
+		"data.customer":    `{"id":"cus_1"}`,
+	}
+	for path, want := range cases {
+		value, ok := LookupJSONPath(data, path)
+		if !ok {
+			t.Fatalf("Path %s not found", path)
+		}
+		if got := JSONValueString(value); got != want {
+			t.Fatalf("Expected %s at path %s, got %s", want, path, got)
+		}
+	}
+
+	for _, path := range []string{"data.missing", "data.items.1.id", "data.customer.id.x"} {
+		if _, ok := LookupJSONPath(data, path); ok {
+			t.Fatalf("Path %s should not be found", path)
+		}
+	}
+}
+
+func TestLookupJSONPathKeepsLargeNumbers(t *testing.T) {
+	value, ok := LookupJSONPath([]byte(`{"id": 9007199254740993, "amount": 1.50}`), "id")
+	if !ok || JSONValueString(value) != "9007199254740993" {
+		t.Fatalf("Expected 9007199254740993, got %v", value)
+	}
+	value, _ = LookupJSONPath([]byte(`{"id": 9007199254740993, "amount": 1.50}`), "amount")
+	if JSONValueString(value) != "1.50" {
+		t.Fatalf("Expected the number as written, got %v", value)
+	}
+}